@@ -0,0 +1,174 @@
+package supabaseorm
+
+import (
+	"context"
+	"fmt"
+)
+
+// AdminClient provides the auth admin API for managing users.
+// All admin endpoints require the service role key.
+type AdminClient struct {
+	client *Client
+}
+
+// AdminUserAttributes represents the request body for creating or updating a user as admin
+type AdminUserAttributes struct {
+	Email        string                 `json:"email,omitempty"`
+	Password     string                 `json:"password,omitempty"`
+	Phone        string                 `json:"phone,omitempty"`
+	EmailConfirm bool                   `json:"email_confirm,omitempty"`
+	PhoneConfirm bool                   `json:"phone_confirm,omitempty"`
+	UserMetadata map[string]interface{} `json:"user_metadata,omitempty"`
+	AppMetadata  map[string]interface{} `json:"app_metadata,omitempty"`
+	BanDuration  string                 `json:"ban_duration,omitempty"`
+}
+
+// listUsersResponse represents the response from listing users
+type listUsersResponse struct {
+	Users []User `json:"users"`
+}
+
+// Admin returns the AdminClient for user management
+func (a *Auth) Admin() *AdminClient {
+	return &AdminClient{
+		client: a.client,
+	}
+}
+
+// serviceBearer returns the Authorization header for admin requests,
+// using the service key when set and the client key otherwise
+func (ad *AdminClient) serviceBearer() string {
+	key := ad.client.serviceKey
+	if key == "" {
+		key = ad.client.apiKey
+	}
+	return fmt.Sprintf("Bearer %s", key)
+}
+
+// CreateUser creates a new user
+func (ad *AdminClient) CreateUser(ctx context.Context, attrs AdminUserAttributes) (*User, error) {
+	endpoint := fmt.Sprintf("%s/auth/v1/admin/users", ad.client.baseURL)
+
+	resp, err := ad.client.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Authorization", ad.serviceBearer()).
+		SetBody(attrs).
+		SetResult(&User{}).
+		Post(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("auth error: %s", resp.String())
+	}
+
+	user, ok := resp.Result().(*User)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse user response")
+	}
+
+	return user, nil
+}
+
+// GetUserByID gets a user by id
+func (ad *AdminClient) GetUserByID(ctx context.Context, id string) (*User, error) {
+	endpoint := fmt.Sprintf("%s/auth/v1/admin/users/%s", ad.client.baseURL, id)
+
+	resp, err := ad.client.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Authorization", ad.serviceBearer()).
+		SetResult(&User{}).
+		Get(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("auth error: %s", resp.String())
+	}
+
+	user, ok := resp.Result().(*User)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse user response")
+	}
+
+	return user, nil
+}
+
+// ListUsers lists users one page at a time; pages start at 1
+func (ad *AdminClient) ListUsers(ctx context.Context, page, perPage int) ([]User, error) {
+	endpoint := fmt.Sprintf("%s/auth/v1/admin/users", ad.client.baseURL)
+
+	resp, err := ad.client.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Authorization", ad.serviceBearer()).
+		SetQueryParam("page", fmt.Sprintf("%d", page)).
+		SetQueryParam("per_page", fmt.Sprintf("%d", perPage)).
+		SetResult(&listUsersResponse{}).
+		Get(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("auth error: %s", resp.String())
+	}
+
+	list, ok := resp.Result().(*listUsersResponse)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse users response")
+	}
+
+	return list.Users, nil
+}
+
+// UpdateUserByID updates a user by id
+func (ad *AdminClient) UpdateUserByID(ctx context.Context, id string, attrs AdminUserAttributes) (*User, error) {
+	endpoint := fmt.Sprintf("%s/auth/v1/admin/users/%s", ad.client.baseURL, id)
+
+	resp, err := ad.client.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Authorization", ad.serviceBearer()).
+		SetBody(attrs).
+		SetResult(&User{}).
+		Put(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("auth error: %s", resp.String())
+	}
+
+	user, ok := resp.Result().(*User)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse user response")
+	}
+
+	return user, nil
+}
+
+// DeleteUser deletes a user by id
+func (ad *AdminClient) DeleteUser(ctx context.Context, id string) error {
+	endpoint := fmt.Sprintf("%s/auth/v1/admin/users/%s", ad.client.baseURL, id)
+
+	resp, err := ad.client.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Authorization", ad.serviceBearer()).
+		Delete(endpoint)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("auth error: %s", resp.String())
+	}
+
+	return nil
+}
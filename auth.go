@@ -2,13 +2,19 @@ package supabaseorm
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 )
 
 // Auth provides methods for authentication with Supabase
 type Auth struct {
 	client *Client
+	jwks   jwksCache
 }
 
 // AuthResponse represents the response from authentication operations
@@ -47,10 +53,12 @@ type SignUpRequest struct {
 
 // SignInRequest represents the request body for signing in
 type SignInRequest struct {
-	Email      string `json:"email"`
+	Email      string `json:"email,omitempty"`
 	Password   string `json:"password,omitempty"`
 	Phone      string `json:"phone,omitempty"`
 	CreateUser bool   `json:"create_user,omitempty"`
+	// Channel selects how a phone OTP is delivered: "sms" (default) or "whatsapp"
+	Channel string `json:"channel,omitempty"`
 }
 
 // VerifyRequest represents the request body for verifying OTP
@@ -60,16 +68,45 @@ type VerifyRequest struct {
 	Type  string `json:"type"`
 }
 
+// VerifyOTPRequest represents the request body for verifying an email or SMS OTP
+type VerifyOTPRequest struct {
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Token string `json:"token"`
+	// Type is one of EmailOTPType, MagicLinkType, SignupType, SMSType or RecoveryType
+	Type string `json:"type"`
+}
+
 // ResetPasswordRequest represents the request body for resetting password
 type ResetPasswordRequest struct {
 	Email string `json:"email"`
 }
 
+// ResendRequest represents the request body for re-sending a confirmation
+type ResendRequest struct {
+	// Type is SignupType, EmailChangeType, SMSType or PhoneChangeType
+	Type  string `json:"type"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	// RedirectTo is where the confirmation link redirects to
+	RedirectTo string `json:"-"`
+}
+
 // UpdatePasswordRequest represents the request body for updating password
 type UpdatePasswordRequest struct {
 	Password string `json:"password"`
 }
 
+// UpdateUserRequest represents the request body for updating the current user
+type UpdateUserRequest struct {
+	Email    string `json:"email,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Nonce is required to change the password when secure password change is enabled
+	Nonce        string                 `json:"nonce,omitempty"`
+	UserMetadata map[string]interface{} `json:"data,omitempty"`
+}
+
 // RefreshTokenRequest represents the request body for refreshing token
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
@@ -140,11 +177,13 @@ func (a *Auth) SignInWithPassword(ctx context.Context, req SignInRequest) (*Auth
 	return authResp, nil
 }
 
-// SignInWithOTP sends a one-time password to the user's email
+// SignInWithOTP sends a one-time password or magic link to the user's email,
+// or an OTP by SMS when Phone is set instead
 func (a *Auth) SignInWithOTP(ctx context.Context, req SignInRequest) error {
 	endpoint := fmt.Sprintf("%s/auth/v1/otp", a.client.baseURL)
 
 	resp, err := a.client.httpClient.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(req).
 		Post(endpoint)
@@ -162,9 +201,19 @@ func (a *Auth) SignInWithOTP(ctx context.Context, req SignInRequest) error {
 
 // Verify verifies a one-time password
 func (a *Auth) Verify(ctx context.Context, req VerifyRequest) (*AuthResponse, error) {
+	return a.VerifyOTP(ctx, VerifyOTPRequest{
+		Email: req.Email,
+		Token: req.Token,
+		Type:  req.Type,
+	})
+}
+
+// VerifyOTP exchanges an email or SMS one-time password for a session
+func (a *Auth) VerifyOTP(ctx context.Context, req VerifyOTPRequest) (*AuthResponse, error) {
 	endpoint := fmt.Sprintf("%s/auth/v1/verify", a.client.baseURL)
 
 	resp, err := a.client.httpClient.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(req).
 		SetResult(&AuthResponse{}).
@@ -191,12 +240,50 @@ func (a *Auth) Verify(ctx context.Context, req VerifyRequest) (*AuthResponse, er
 
 // ResetPassword sends a password reset email
 func (a *Auth) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	return a.ResetPasswordForEmail(ctx, req.Email, "")
+}
+
+// ResetPasswordForEmail sends a password recovery email. The link in the email
+// redirects to redirectTo when set, otherwise to the project's site URL.
+func (a *Auth) ResetPasswordForEmail(ctx context.Context, email, redirectTo string) error {
 	endpoint := fmt.Sprintf("%s/auth/v1/recover", a.client.baseURL)
 
-	resp, err := a.client.httpClient.R().
+	req := a.client.httpClient.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetBody(req).
-		Post(endpoint)
+		SetBody(ResetPasswordRequest{Email: email})
+
+	if redirectTo != "" {
+		req.SetQueryParam("redirect_to", redirectTo)
+	}
+
+	resp, err := req.Post(endpoint)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("auth error: %s", resp.String())
+	}
+
+	return nil
+}
+
+// Resend re-sends a signup confirmation, email change confirmation or SMS OTP
+func (a *Auth) Resend(ctx context.Context, req ResendRequest) error {
+	endpoint := fmt.Sprintf("%s/auth/v1/resend", a.client.baseURL)
+
+	r := a.client.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(req)
+
+	if req.RedirectTo != "" {
+		r.SetQueryParam("redirect_to", req.RedirectTo)
+	}
+
+	resp, err := r.Post(endpoint)
 
 	if err != nil {
 		return err
@@ -211,23 +298,37 @@ func (a *Auth) ResetPassword(ctx context.Context, req ResetPasswordRequest) erro
 
 // UpdatePassword updates the user's password
 func (a *Auth) UpdatePassword(ctx context.Context, req UpdatePasswordRequest, token string) error {
+	_, err := a.UpdateUser(ctx, token, UpdateUserRequest{Password: req.Password})
+	return err
+}
+
+// UpdateUser updates the email, phone, password or metadata of the user
+// the access token belongs to, and returns the updated user
+func (a *Auth) UpdateUser(ctx context.Context, accessToken string, req UpdateUserRequest) (*User, error) {
 	endpoint := fmt.Sprintf("%s/auth/v1/user", a.client.baseURL)
 
 	resp, err := a.client.httpClient.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", fmt.Sprintf("Bearer %s", token)).
+		SetHeader("Authorization", fmt.Sprintf("Bearer %s", accessToken)).
 		SetBody(req).
+		SetResult(&User{}).
 		Put(endpoint)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("auth error: %s", resp.String())
+		return nil, fmt.Errorf("auth error: %s", resp.String())
 	}
 
-	return nil
+	user, ok := resp.Result().(*User)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse user response")
+	}
+
+	return user, nil
 }
 
 // RefreshToken refreshes the access token
@@ -264,6 +365,7 @@ func (a *Auth) GetUser(ctx context.Context, token string) (*User, error) {
 	endpoint := fmt.Sprintf("%s/auth/v1/user", a.client.baseURL)
 
 	resp, err := a.client.httpClient.R().
+		SetContext(ctx).
 		SetHeader("Authorization", fmt.Sprintf("Bearer %s", token)).
 		SetResult(&User{}).
 		Get(endpoint)
@@ -303,6 +405,105 @@ func (a *Auth) SignOut(ctx context.Context, token string) error {
 	return nil
 }
 
+// OAuthOptions configures the OAuth sign-in URL
+type OAuthOptions struct {
+	// RedirectTo is where the user is sent after signing in with the provider
+	RedirectTo string
+	// Scopes requested from the provider, e.g. "repo" for GitHub
+	Scopes []string
+	// CodeChallenge enables the PKCE flow, see GeneratePKCE
+	CodeChallenge string
+	// CodeChallengeMethod is "s256" (default when CodeChallenge is set) or "plain"
+	CodeChallengeMethod string
+	// QueryParams are passed through to the provider
+	QueryParams map[string]string
+}
+
+// pkceRequest represents the request body for exchanging a PKCE auth code
+type pkceRequest struct {
+	AuthCode     string `json:"auth_code"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// GetOAuthSignInURL builds the URL that starts the OAuth flow with a provider such as
+// "github" or "google". Redirect the user to it; with PKCE, keep the code verifier
+// to call ExchangeCodeForSession from the callback.
+func (a *Auth) GetOAuthSignInURL(provider string, opts OAuthOptions) (string, error) {
+	if provider == "" {
+		return "", fmt.Errorf("oauth provider is required")
+	}
+
+	params := url.Values{}
+	params.Set("provider", provider)
+
+	if opts.RedirectTo != "" {
+		params.Set("redirect_to", opts.RedirectTo)
+	}
+
+	if len(opts.Scopes) > 0 {
+		params.Set("scopes", strings.Join(opts.Scopes, " "))
+	}
+
+	if opts.CodeChallenge != "" {
+		method := opts.CodeChallengeMethod
+		if method == "" {
+			method = "s256"
+		}
+		params.Set("code_challenge", opts.CodeChallenge)
+		params.Set("code_challenge_method", method)
+	}
+
+	for k, v := range opts.QueryParams {
+		params.Set(k, v)
+	}
+
+	return fmt.Sprintf("%s/auth/v1/authorize?%s", a.client.baseURL, params.Encode()), nil
+}
+
+// GeneratePKCE returns a random code verifier and its S256 code challenge
+func GeneratePKCE() (verifier, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return verifier, challenge, nil
+}
+
+// ExchangeCodeForSession exchanges the auth code from a PKCE OAuth callback for a session
+func (a *Auth) ExchangeCodeForSession(ctx context.Context, code, codeVerifier string) (*AuthResponse, error) {
+	endpoint := fmt.Sprintf("%s/auth/v1/token?grant_type=pkce", a.client.baseURL)
+
+	resp, err := a.client.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(pkceRequest{AuthCode: code, CodeVerifier: codeVerifier}).
+		SetResult(&AuthResponse{}).
+		Post(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("auth error: %s", resp.String())
+	}
+
+	authResp, ok := resp.Result().(*AuthResponse)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse auth response")
+	}
+
+	// Calculate expires_at
+	authResp.ExpiresAt = time.Now().Add(time.Second * time.Duration(authResp.ExpiresIn))
+
+	return authResp, nil
+}
+
 // MagicLinkType is the type for magic link authentication
 const MagicLinkType = "magiclink"
 
@@ -311,3 +512,15 @@ const SMSType = "sms"
 
 // RecoveryType is the type for recovery authentication
 const RecoveryType = "recovery"
+
+// EmailOTPType is the type for email one-time password authentication
+const EmailOTPType = "email"
+
+// SignupType is the type for signup confirmation
+const SignupType = "signup"
+
+// EmailChangeType is the type for email change confirmation
+const EmailChangeType = "email_change"
+
+// PhoneChangeType is the type for phone change confirmation
+const PhoneChangeType = "phone_change"
@@ -2,6 +2,11 @@ package supabaseorm
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"testing"
 )
 
@@ -192,3 +197,277 @@ func TestAuthConstants(t *testing.T) {
 		t.Errorf("Expected RecoveryType to be 'recovery', got '%s'", RecoveryType)
 	}
 }
+
+func TestAdminUsers(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer service-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost:
+			var attrs AdminUserAttributes
+			json.NewDecoder(r.Body).Decode(&attrs)
+			w.Write([]byte(`{"id":"u1","email":"` + attrs.Email + `"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/auth/v1/admin/users":
+			w.Write([]byte(`{"users":[{"id":"u1"},{"id":"u2"}],"aud":"authenticated"}`))
+		case r.Method == http.MethodDelete:
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`{"id":"u1","email":"new@example.com"}`))
+		}
+	}))
+	defer server.Close()
+
+	admin := New(server.URL, "anon-key", WithServiceKey("service-key")).Auth().Admin()
+	ctx := context.Background()
+
+	user, err := admin.CreateUser(ctx, AdminUserAttributes{Email: "test@example.com", Password: "secret", EmailConfirm: true})
+	if err != nil || user.ID != "u1" || user.Email != "test@example.com" {
+		t.Fatalf("CreateUser() = %+v, %v", user, err)
+	}
+
+	users, err := admin.ListUsers(ctx, 2, 50)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("ListUsers() = %+v, %v", users, err)
+	}
+
+	if _, err := admin.GetUserByID(ctx, "u1"); err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+
+	if _, err := admin.UpdateUserByID(ctx, "u1", AdminUserAttributes{Email: "new@example.com"}); err != nil {
+		t.Fatalf("UpdateUserByID() error = %v", err)
+	}
+
+	if err := admin.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+
+	expected := []string{
+		"POST /auth/v1/admin/users",
+		"GET /auth/v1/admin/users?page=2&per_page=50",
+		"GET /auth/v1/admin/users/u1",
+		"PUT /auth/v1/admin/users/u1",
+		"DELETE /auth/v1/admin/users/u1",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("requests = %v, want %v", requests, expected)
+	}
+}
+
+func TestOTPSignIn(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/auth/v1/verify" {
+			w.Write([]byte(`{"access_token":"jwt","token_type":"bearer","expires_in":3600,"refresh_token":"refresh","user":{"id":"u1","phone":"15551234567"}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	auth := New(server.URL, "test-api-key").Auth()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		req      SignInRequest
+		expected map[string]interface{}
+	}{
+		{
+			name:     "email magic link",
+			req:      SignInRequest{Email: "test@example.com", CreateUser: true},
+			expected: map[string]interface{}{"email": "test@example.com", "create_user": true},
+		},
+		{
+			name:     "sms",
+			req:      SignInRequest{Phone: "15551234567", Channel: "sms"},
+			expected: map[string]interface{}{"phone": "15551234567", "channel": "sms"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := auth.SignInWithOTP(ctx, tt.req); err != nil {
+				t.Fatalf("SignInWithOTP() error = %v", err)
+			}
+			if path != "/auth/v1/otp" || !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("SignInWithOTP() sent %s %v, want /auth/v1/otp %v", path, body, tt.expected)
+			}
+		})
+	}
+
+	session, err := auth.VerifyOTP(ctx, VerifyOTPRequest{Phone: "15551234567", Token: "123456", Type: SMSType})
+	if err != nil {
+		t.Fatalf("VerifyOTP() error = %v", err)
+	}
+
+	expected := map[string]interface{}{"phone": "15551234567", "token": "123456", "type": "sms"}
+	if path != "/auth/v1/verify" || !reflect.DeepEqual(body, expected) {
+		t.Errorf("VerifyOTP() sent %s %v, want /auth/v1/verify %v", path, body, expected)
+	}
+	if session.AccessToken != "jwt" || session.User.ID != "u1" || session.ExpiresAt.IsZero() {
+		t.Errorf("VerifyOTP() = %+v, want session for u1", session)
+	}
+}
+
+func TestGetOAuthSignInURL(t *testing.T) {
+	auth := New("https://example.supabase.co", "test-api-key").Auth()
+
+	signInURL, err := auth.GetOAuthSignInURL("github", OAuthOptions{
+		RedirectTo:    "https://app.example.com/callback",
+		Scopes:        []string{"repo", "gist"},
+		CodeChallenge: "challenge",
+	})
+	if err != nil {
+		t.Fatalf("GetOAuthSignInURL() error = %v", err)
+	}
+
+	u, err := url.Parse(signInURL)
+	if err != nil {
+		t.Fatalf("GetOAuthSignInURL() returned invalid URL %q", signInURL)
+	}
+	if u.Host != "example.supabase.co" || u.Path != "/auth/v1/authorize" {
+		t.Errorf("GetOAuthSignInURL() = %v, want /auth/v1/authorize on the project host", signInURL)
+	}
+
+	expected := url.Values{
+		"provider":              {"github"},
+		"redirect_to":           {"https://app.example.com/callback"},
+		"scopes":                {"repo gist"},
+		"code_challenge":        {"challenge"},
+		"code_challenge_method": {"s256"},
+	}
+	if !reflect.DeepEqual(u.Query(), expected) {
+		t.Errorf("GetOAuthSignInURL() query = %v, want %v", u.Query(), expected)
+	}
+
+	if _, err := auth.GetOAuthSignInURL("", OAuthOptions{}); err == nil {
+		t.Error("GetOAuthSignInURL() without provider should fail")
+	}
+}
+
+func TestExchangeCodeForSession(t *testing.T) {
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil || verifier == "" || challenge == "" || verifier == challenge {
+		t.Fatalf("GeneratePKCE() = %q, %q, %v", verifier, challenge, err)
+	}
+
+	var query string
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"jwt","expires_in":3600,"refresh_token":"refresh","user":{"id":"u1"}}`))
+	}))
+	defer server.Close()
+
+	auth := New(server.URL, "test-api-key").Auth()
+
+	session, err := auth.ExchangeCodeForSession(context.Background(), "auth-code", verifier)
+	if err != nil {
+		t.Fatalf("ExchangeCodeForSession() error = %v", err)
+	}
+
+	if query != "grant_type=pkce" {
+		t.Errorf("ExchangeCodeForSession() query = %q, want grant_type=pkce", query)
+	}
+	if body["auth_code"] != "auth-code" || body["code_verifier"] != verifier {
+		t.Errorf("ExchangeCodeForSession() body = %v", body)
+	}
+	if session.AccessToken != "jwt" || session.User.ID != "u1" {
+		t.Errorf("ExchangeCodeForSession() = %+v, want session for u1", session)
+	}
+}
+
+func TestGetAndUpdateUser(t *testing.T) {
+	var method, path, authorization string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"u1","email":"new@example.com","user_metadata":{"name":"Ann"}}`))
+	}))
+	defer server.Close()
+
+	auth := New(server.URL, "anon-key").Auth()
+	ctx := context.Background()
+
+	user, err := auth.GetUser(ctx, "user-jwt")
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if method != http.MethodGet || path != "/auth/v1/user" || authorization != "Bearer user-jwt" {
+		t.Errorf("GetUser() sent %s %s with %q", method, path, authorization)
+	}
+	if user.ID != "u1" {
+		t.Errorf("GetUser() = %+v, want u1", user)
+	}
+
+	user, err = auth.UpdateUser(ctx, "user-jwt", UpdateUserRequest{
+		Email:        "new@example.com",
+		UserMetadata: map[string]interface{}{"name": "Ann"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if method != http.MethodPut || path != "/auth/v1/user" || authorization != "Bearer user-jwt" {
+		t.Errorf("UpdateUser() sent %s %s with %q", method, path, authorization)
+	}
+
+	expected := map[string]interface{}{"email": "new@example.com", "data": map[string]interface{}{"name": "Ann"}}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("UpdateUser() body = %v, want %v", body, expected)
+	}
+	if user.Email != "new@example.com" || user.UserMetadata["name"] != "Ann" {
+		t.Errorf("UpdateUser() = %+v", user)
+	}
+}
+
+func TestRecoveryAndResend(t *testing.T) {
+	var path, redirectTo string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		redirectTo = r.URL.Query().Get("redirect_to")
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	auth := New(server.URL, "anon-key").Auth()
+	ctx := context.Background()
+
+	if err := auth.ResetPasswordForEmail(ctx, "test@example.com", "https://app.example.com/reset"); err != nil {
+		t.Fatalf("ResetPasswordForEmail() error = %v", err)
+	}
+	expected := map[string]interface{}{"email": "test@example.com"}
+	if path != "/auth/v1/recover" || redirectTo != "https://app.example.com/reset" || !reflect.DeepEqual(body, expected) {
+		t.Errorf("ResetPasswordForEmail() sent %s %v redirect %q", path, body, redirectTo)
+	}
+
+	if err := auth.Resend(ctx, ResendRequest{Type: SignupType, Email: "test@example.com"}); err != nil {
+		t.Fatalf("Resend() error = %v", err)
+	}
+	expected = map[string]interface{}{"type": "signup", "email": "test@example.com"}
+	if path != "/auth/v1/resend" || redirectTo != "" || !reflect.DeepEqual(body, expected) {
+		t.Errorf("Resend() sent %s %v redirect %q", path, body, redirectTo)
+	}
+}
@@ -0,0 +1,97 @@
+package supabaseorm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// WithCircuitBreaker stops sending requests to a failing backend: after threshold
+// consecutive failures, each within cooldown of the previous one, queries fail fast with
+// ErrCircuitOpen for cooldown. The next query is then sent as a probe; if it succeeds
+// the breaker closes, otherwise it stays open for another cooldown. Network errors and
+// 429 or 5xx responses count as failures; other error responses, such as a constraint
+// violation, show the backend is up and reset the count. Copies of the client made
+// with WithSession and similar methods share the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		c.breaker = &circuitBreaker{
+			threshold: threshold,
+			cooldown:  cooldown,
+			now:       time.Now,
+		}
+	}
+}
+
+// circuitBreaker counts consecutive failures of the requests sent by a client
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	// lastFailure is when the last failure was recorded
+	lastFailure time.Time
+	// openUntil is when an open breaker lets a probe through; zero while closed
+	openUntil time.Time
+	// probing is set while the probe of a half-open breaker is in flight
+	probing bool
+	now     func() time.Time
+}
+
+// allow returns ErrCircuitOpen while the breaker is open or its probe is in flight.
+// probe is true for the single request admitted once the cooldown has passed; it must
+// be handed back to record along with that request's outcome.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return false, nil
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return false, ErrCircuitOpen
+	}
+
+	b.probing = true
+	return true, nil
+}
+
+// record counts the outcome of a request let through by allow. While the breaker is
+// open only the probe decides its state; requests admitted before it opened may still
+// be finishing and say nothing about the backend now.
+func (b *circuitBreaker) record(probe bool, resp *resty.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	} else if !b.openUntil.IsZero() {
+		return
+	}
+
+	// A request abandoned by its caller says nothing about the backend
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil && resp.StatusCode() != http.StatusTooManyRequests && resp.StatusCode() < 500 {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+
+	now := b.now()
+	if now.Sub(b.lastFailure) > b.cooldown {
+		b.failures = 0
+	}
+	b.failures++
+	b.lastFailure = now
+
+	if probe || b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
@@ -0,0 +1,127 @@
+package supabaseorm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	requests := 0
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"message":"unavailable"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	client := New(server.URL, "fake-api-key", WithCircuitBreaker(3, 30*time.Second))
+	client.breaker.now = func() time.Time { return now }
+
+	get := func() error {
+		var rows []map[string]interface{}
+		return client.Table("users").Get(&rows)
+	}
+
+	// Three failures trip the breaker
+	for i := 0; i < 3; i++ {
+		if err := get(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("request %d error = %v, want the server error", i, err)
+		}
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() with the breaker open = %v, want ErrCircuitOpen", err)
+	}
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3 with the breaker open", requests)
+	}
+
+	// After the cooldown a failing probe reopens it
+	now = now.Add(31 * time.Second)
+	if err := get(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe error = %v, want the server error", err)
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() after a failed probe = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful probe closes it
+	failing = false
+	now = now.Add(31 * time.Second)
+	for i := 0; i < 2; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("Get() after recovery = %v, want no error", err)
+		}
+	}
+	if requests != 6 {
+		t.Errorf("requests = %d, want 6", requests)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"code":"23505","message":"duplicate key"}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key", WithCircuitBreaker(2, time.Minute))
+	for i := 0; i < 5; i++ {
+		if err := client.Table("users").Insert(map[string]interface{}{"id": 1}); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("insert %d error = %v, want the conflict", i, err)
+		}
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := &circuitBreaker{threshold: 1, cooldown: 30 * time.Second, now: func() time.Time { return now }}
+	ok := &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusOK}}
+	unavailable := &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusServiceUnavailable}}
+
+	// A request admitted before the breaker trips is still in flight
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("allow() while closed = %v", err)
+	}
+	b.record(false, unavailable, nil)
+
+	now = now.Add(31 * time.Second)
+	probe, err := b.allow()
+	if err != nil || !probe {
+		t.Fatalf("allow() after the cooldown = %v, %v, want the probe", probe, err)
+	}
+
+	// The straggler finishing first must not close the breaker or release the probe
+	b.record(false, ok, nil)
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() with the probe in flight = %v, want ErrCircuitOpen", err)
+	}
+
+	// The probe's failure reopens it
+	b.record(probe, unavailable, nil)
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow() after a failed probe = %v, want ErrCircuitOpen", err)
+	}
+
+	now = now.Add(31 * time.Second)
+	probe, err = b.allow()
+	if err != nil || !probe {
+		t.Fatalf("allow() after the second cooldown = %v, %v, want the probe", probe, err)
+	}
+	b.record(probe, ok, nil)
+	if probe, err := b.allow(); err != nil || probe {
+		t.Fatalf("allow() after a successful probe = %v, %v, want closed", probe, err)
+	}
+}
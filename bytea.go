@@ -0,0 +1,200 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// ByteaEncoding selects how []byte fields are represented in request and response bodies
+type ByteaEncoding int
+
+const (
+	// ByteaBase64 keeps the encoding/json default of base64 strings, which suits
+	// json and jsonb columns that store binary data as text
+	ByteaBase64 ByteaEncoding = iota
+	// ByteaHex writes []byte fields in the \x hex format PostgreSQL uses for bytea
+	// columns, and decodes \x hex values read back from them
+	ByteaHex
+)
+
+// WithByteaEncoding sets how []byte fields of table rows are encoded, see ByteaEncoding
+func WithByteaEncoding(encoding ByteaEncoding) ClientOption {
+	return func(c *Client) {
+		c.byteaEncoding = encoding
+	}
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// encodeBody marshals body for the client's ByteaEncoding and ColumnNamer: []byte
+// fields are written in the \x hex format, and untagged struct fields are renamed
+func encodeBody(body interface{}, encoding ByteaEncoding, namer ColumnNamer) ([]byte, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeGeneric(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	t, v := reflect.TypeOf(body), reflect.ValueOf(body)
+	if encoding == ByteaHex {
+		data = rewriteBytea(data, t, v, true)
+	}
+	if namer != nil {
+		data = renameColumns(data, t, v, namer, true)
+	}
+
+	return json.Marshal(data)
+}
+
+// decodeBody unmarshals raw into result, reversing encodeBody
+func decodeBody(raw []byte, result interface{}, encoding ByteaEncoding, namer ColumnNamer) error {
+	data, err := decodeGeneric(raw)
+	if err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(result)
+	if namer != nil {
+		data = renameColumns(data, t, reflect.Value{}, namer, false)
+	}
+	if encoding == ByteaHex {
+		data = rewriteBytea(data, t, reflect.Value{}, false)
+	}
+
+	converted, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(converted, result)
+}
+
+// decodeGeneric decodes json into maps and slices, keeping numbers exact
+func decodeGeneric(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var data interface{}
+	err := decoder.Decode(&data)
+	return data, err
+}
+
+// rewriteBytea walks decoded json alongside the Go type it maps to, converting the
+// strings of []byte fields between base64 and \x hex. The value is used when writing,
+// so that interface fields such as the values of StructToMap resolve to their dynamic type.
+func rewriteBytea(data interface{}, t reflect.Type, v reflect.Value, toHex bool) interface{} {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		switch {
+		case v.IsValid():
+			if v.IsNil() {
+				return data
+			}
+			v = v.Elem()
+			t = v.Type()
+		case t.Kind() == reflect.Ptr:
+			t = t.Elem()
+		default:
+			return data
+		}
+	}
+
+	// Types with their own json encoding, e.g. time.Time, are left alone
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return data
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if s, ok := data.(string); ok {
+				return convertBytea(s, toHex)
+			}
+			return data
+		}
+		fallthrough
+	case reflect.Array:
+		items, ok := data.([]interface{})
+		if !ok {
+			return data
+		}
+		for i := range items {
+			var item reflect.Value
+			if v.IsValid() && i < v.Len() {
+				item = v.Index(i)
+			}
+			items[i] = rewriteBytea(items[i], t.Elem(), item, toHex)
+		}
+	case reflect.Map:
+		obj, ok := data.(map[string]interface{})
+		if !ok || t.Key().Kind() != reflect.String {
+			return data
+		}
+		for key, item := range obj {
+			var value reflect.Value
+			if v.IsValid() {
+				value = v.MapIndex(reflect.ValueOf(key).Convert(t.Key()))
+			}
+			obj[key] = rewriteBytea(item, t.Elem(), value, toHex)
+		}
+	case reflect.Struct:
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return data
+		}
+		for _, field := range reflect.VisibleFields(t) {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" || (field.Anonymous && name == "") {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			item, ok := obj[name]
+			if !ok {
+				continue
+			}
+
+			var value reflect.Value
+			if v.IsValid() {
+				value, _ = v.FieldByIndexErr(field.Index)
+			}
+			obj[name] = rewriteBytea(item, field.Type, value, toHex)
+		}
+	}
+
+	return data
+}
+
+// convertBytea converts a base64 string to \x hex, or a \x hex string back to base64.
+// Strings that are not in the expected format are returned unchanged.
+func convertBytea(s string, toHex bool) string {
+	if toHex {
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return s
+		}
+		return `\x` + hex.EncodeToString(decoded)
+	}
+
+	if !strings.HasPrefix(s, `\x`) {
+		return s
+	}
+
+	decoded, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return s
+	}
+	return base64.StdEncoding.EncodeToString(decoded)
+}
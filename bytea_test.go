@@ -0,0 +1,75 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testFile struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Content []byte `json:"content"`
+}
+
+func TestByteaHexRoundTrip(t *testing.T) {
+	var stored map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost, http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+			var row map[string]interface{}
+			if err := json.Unmarshal(body, &row); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			stored = row
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			json.NewEncoder(w).Encode([]map[string]interface{}{stored})
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key", WithByteaEncoding(ByteaHex))
+	content := []byte{0x00, 0x01, 0xfe, 0xff}
+
+	if err := client.Table("files").Insert(testFile{ID: 1, Name: "blob", Content: content}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if got := stored["content"]; got != `\x0001feff` {
+		t.Errorf("inserted content = %v, want %q", got, `\x0001feff`)
+	}
+	if got := stored["name"]; got != "blob" {
+		t.Errorf("inserted name = %v, want blob", got)
+	}
+
+	var files []testFile
+	if err := client.Table("files").Get(&files); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(files) != 1 || !bytes.Equal(files[0].Content, content) || files[0].Name != "blob" {
+		t.Errorf("Get() = %+v, want content %v", files, content)
+	}
+
+	// Update sends a map built from the struct, whose values are resolved dynamically
+	updated := []byte("hello")
+	if err := client.Table("files").Where("id", "eq", 1).Update(testFile{ID: 1, Name: "greeting", Content: updated}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := stored["content"]; got != `\x68656c6c6f` {
+		t.Errorf("updated content = %v, want %q", got, `\x68656c6c6f`)
+	}
+
+	// The default encoding keeps base64
+	if err := New(server.URL, "fake-api-key").Table("files").Insert(testFile{ID: 2, Content: content}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if got := stored["content"]; got != "AAH+/w==" {
+		t.Errorf("inserted content = %v, want base64", got)
+	}
+}
@@ -0,0 +1,174 @@
+package supabaseorm
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// queryCache keeps the responses of reads for a limited time, see WithQueryCache
+type queryCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*cacheEntry
+	now        func() time.Time
+}
+
+// cacheEntry is a cached response along with the table it was read from
+type cacheEntry struct {
+	table   string
+	status  int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+}
+
+// WithQueryCache returns a copy of the client that caches the responses of reads in
+// memory for ttl, keeping at most maxEntries responses and evicting the oldest first.
+// Identical queries, with the same URL and headers, are then served from the cache.
+// A write through the client to a table drops the cached reads of that table; writes
+// made elsewhere are only seen once the entries expire, so reserve the cache for
+// reference data that rarely changes. Use NoCache and CacheFor to override it per query.
+func (c *Client) WithQueryCache(ttl time.Duration, maxEntries int) *Client {
+	clone := *c
+	clone.queryCache = &queryCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*cacheEntry),
+		now:        time.Now,
+	}
+	return &clone
+}
+
+// NoCache sends the query to the server even if the client caches reads,
+// and leaves its response out of the cache
+func (q *QueryBuilder) NoCache() *QueryBuilder {
+	q.noCache = true
+	return q
+}
+
+// CacheFor caches the response of the query for d instead of the client's TTL.
+// It has no effect unless the client was configured with WithQueryCache.
+func (q *QueryBuilder) CacheFor(d time.Duration) *QueryBuilder {
+	q.cacheTTL = d
+	return q
+}
+
+// cacheKey returns the key of a read in the client's cache, or "" when it isn't cached
+func (q *QueryBuilder) cacheKey(endpoint string, req *resty.Request) string {
+	if q.client == nil || q.client.queryCache == nil || q.noCache || q.method != http.MethodGet {
+		return ""
+	}
+
+	var key strings.Builder
+	key.WriteString(endpoint)
+	key.WriteString("?")
+	key.WriteString(req.QueryParam.Encode())
+
+	// Headers such as Authorization, Range and Prefer change the response
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(req.Header[name], ", "))
+	}
+
+	return key.String()
+}
+
+// get returns a copy of the cached response for key if it hasn't expired
+func (c *queryCache) get(key string) (*resty.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	resp := &resty.Response{RawResponse: &http.Response{
+		StatusCode: entry.status,
+		Header:     entry.header.Clone(),
+	}}
+	return resp.SetBody(entry.body), true
+}
+
+// put caches a successful response for ttl, or the cache's TTL when ttl is zero
+func (c *queryCache) put(key, table string, resp *resty.Response, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	if ttl <= 0 || c.maxEntries <= 0 || resp.StatusCode() != http.StatusOK {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+
+	c.entries[key] = &cacheEntry{
+		table:   table,
+		status:  resp.StatusCode(),
+		header:  resp.Header().Clone(),
+		body:    append([]byte(nil), resp.Body()...),
+		stored:  now,
+		expires: now.Add(ttl),
+	}
+}
+
+// evict drops the expired entries, or the oldest one if none has expired
+func (c *queryCache) evict(now time.Time) {
+	var oldest string
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.stored.Before(c.entries[oldest].stored) {
+			oldest = key
+		}
+	}
+
+	if len(c.entries) >= c.maxEntries && oldest != "" {
+		delete(c.entries, oldest)
+	}
+}
+
+// invalidate drops the cached reads of table
+func (c *queryCache) invalidate(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.table == table {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// clear drops every cached response
+func (c *queryCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*cacheEntry)
+}
@@ -0,0 +1,84 @@
+package supabaseorm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryCache(t *testing.T) {
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			reads++
+			w.Write([]byte(`[{"id":1,"name":"John"}]`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := New(server.URL, "fake-api-key").WithQueryCache(time.Minute, 10)
+	client.queryCache.now = func() time.Time { return now }
+
+	read := func(q *QueryBuilder) {
+		t.Helper()
+		var users []TestUser
+		if err := q.Get(&users); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if len(users) != 1 || users[0].Name != "John" {
+			t.Fatalf("Get() = %+v", users)
+		}
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		reads = 0
+		read(client.Table("countries").Where("id", "eq", 1))
+		read(client.Table("countries").Where("id", "eq", 1))
+		if reads != 1 {
+			t.Errorf("server reads = %d, want 1", reads)
+		}
+
+		read(client.Table("countries").Where("id", "eq", 2))
+		read(client.Table("countries").Where("id", "eq", 1).NoCache())
+		if reads != 3 {
+			t.Errorf("server reads = %d, want 3 for a different query and NoCache", reads)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		reads = 0
+		read(client.Table("currencies").CacheFor(2 * time.Minute))
+		now = now.Add(90 * time.Second)
+		read(client.Table("currencies").CacheFor(2 * time.Minute))
+		if reads != 1 {
+			t.Errorf("server reads within CacheFor = %d, want 1", reads)
+		}
+
+		now = now.Add(time.Minute)
+		read(client.Table("currencies"))
+		if reads != 2 {
+			t.Errorf("server reads after expiry = %d, want 2", reads)
+		}
+	})
+
+	t.Run("write invalidation", func(t *testing.T) {
+		reads = 0
+		read(client.Table("languages"))
+		read(client.Table("regions"))
+
+		if err := client.Table("languages").Where("id", "eq", 1).Update(map[string]interface{}{"name": "Spanish"}); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		read(client.Table("languages"))
+		read(client.Table("regions"))
+		if reads != 3 {
+			t.Errorf("server reads = %d, want 3 with only languages refetched", reads)
+		}
+	})
+}
@@ -1,17 +1,57 @@
 package supabaseorm
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
-// Client represents a Supabase client
+// Client represents a Supabase client.
+// A Client is safe for concurrent use by multiple goroutines once New returns:
+// its configuration is not modified afterwards, and every query builder and
+// request carries its own state. Options must not be applied to a Client in use.
 type Client struct {
-	baseURL    string
-	apiKey     string
+	baseURL     string
+	apiKey      string
+	anonKey     string
+	serviceKey  string
+	session     string
+	schema      string
+	jwtSecret   string
+	jwtAudience string
+	// defaultSelects maps a table name to the columns selected when a query has no explicit Select
+	defaultSelects map[string][]string
+	// tableHeaders maps a table name to the headers sent with its queries, see WithTableDefaults
+	tableHeaders map[string]map[string]string
+	// maxLimit caps the rows a read may request; maxLimitStrict rejects larger limits instead
+	maxLimit       int
+	maxLimitStrict bool
+	byteaEncoding  ByteaEncoding
+	// columnNamer renames struct fields without a json tag, see WithColumnNamer
+	columnNamer ColumnNamer
+	// defaultOrder is applied to reads without an explicit Order, see WithDefaultOrder
+	defaultOrder string
+	logger       *slog.Logger
+	// tableSchemas caches the columns loaded by DescribeTable
+	tableSchemas *schemaCache
+	// queryCache holds the responses of reads, see WithQueryCache
+	queryCache *queryCache
+	// serverInfo caches the server version, see ServerInfo
+	serverInfo *serverInfoCache
+	// httpTimeout is the default time a query may take, see WithHTTPTimeout
+	httpTimeout time.Duration
+	// breaker fails requests fast while the backend is down, see WithCircuitBreaker
+	breaker    *circuitBreaker
 	httpClient *resty.Client
 	auth       *Auth
 }
@@ -26,6 +66,16 @@ func WithTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
+// WithHTTPTimeout sets the default time a query may take, from sending the request to
+// reading the response. Unlike WithTimeout, which caps every request of the HTTP client,
+// a query can replace it with QueryBuilder.Timeout. A context deadline that comes sooner
+// still wins, and the remaining time is sent as Prefer: timeout, see WithContext.
+func WithHTTPTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.httpTimeout = timeout
+	}
+}
+
 // WithHeaders sets additional headers for the HTTP client
 func WithHeaders(headers map[string]string) ClientOption {
 	return func(c *Client) {
@@ -33,19 +83,254 @@ func WithHeaders(headers map[string]string) ClientOption {
 	}
 }
 
-// New creates a new Supabase client
+// WithCompression toggles gzip for responses. When enabled (the default), the transport
+// sends Accept-Encoding: gzip and decompresses the body as it is read, so the compressed
+// payload is never buffered separately. Disable it for servers that mishandle gzip.
+func WithCompression(enabled bool) ClientOption {
+	return func(c *Client) {
+		if transport, err := c.httpClient.Transport(); err == nil {
+			transport.DisableCompression = !enabled
+		}
+	}
+}
+
+// WithTransportConfig sizes the connection pool of the HTTP transport for concurrent
+// use: up to maxIdleConns idle connections are kept for reuse, all of them available
+// to the Supabase host, at most maxConnsPerHost connections are open to it at once
+// (0 for no limit), and idle connections are closed after idleTimeout. The net/http
+// default of two idle connections per host makes bursts reconnect constantly.
+func WithTransportConfig(maxIdleConns, maxConnsPerHost int, idleTimeout time.Duration) ClientOption {
+	return func(c *Client) {
+		if transport, err := c.httpClient.Transport(); err == nil {
+			transport.MaxIdleConns = maxIdleConns
+			transport.MaxIdleConnsPerHost = maxIdleConns
+			transport.MaxConnsPerHost = maxConnsPerHost
+			transport.IdleConnTimeout = idleTimeout
+		}
+	}
+}
+
+// DefaultCompressionThreshold is the body size in bytes above which
+// WithRequestCompression gzips request bodies
+const DefaultCompressionThreshold = 1024
+
+// WithRequestCompression gzips POST and PATCH bodies larger than DefaultCompressionThreshold
+// and sets Content-Encoding: gzip. It is opt-in because not every server or gateway in front
+// of PostgREST accepts compressed request bodies.
+func WithRequestCompression() ClientOption {
+	return func(c *Client) {
+		c.httpClient.OnBeforeRequest(compressRequestBody(DefaultCompressionThreshold))
+	}
+}
+
+// compressRequestBody returns a middleware that gzips write bodies of at least threshold bytes.
+// Resty runs it again before each retry, when the body is already compressed.
+func compressRequestBody(threshold int) resty.RequestMiddleware {
+	return func(_ *resty.Client, r *resty.Request) error {
+		if r.Body == nil || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			return nil
+		}
+		if r.Header.Get("Content-Encoding") != "" {
+			return nil
+		}
+
+		var raw []byte
+		switch body := r.Body.(type) {
+		case []byte:
+			raw = body
+		case string:
+			raw = []byte(body)
+		case io.Reader:
+			// Streams are sent as-is
+			return nil
+		default:
+			encoded, err := json.Marshal(body)
+			if err != nil {
+				return err
+			}
+			raw = encoded
+		}
+
+		if len(raw) < threshold {
+			return nil
+		}
+
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(raw); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+
+		r.SetHeader("Content-Encoding", "gzip")
+		r.Body = buf.Bytes()
+		return nil
+	}
+}
+
+// WithLogger sets the logger for warnings about risky queries, such as paginating
+// without an order. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithAnonKey sets the anon key sent in the apikey header, when the key passed to New is
+// not the anon key. It takes precedence over that key, which is then not sent at all:
+// the anon key is also the bearer token unless WithServiceKey or WithSession sets one.
+// The result doesn't depend on the order of WithAnonKey and WithServiceKey.
+func WithAnonKey(key string) ClientOption {
+	return func(c *Client) {
+		c.anonKey = key
+		c.setAuthHeaders()
+	}
+}
+
+// WithServiceKey sets the service role key sent as the bearer token, so requests
+// bypass row level security. A user session set with WithSession still takes precedence.
+func WithServiceKey(key string) ClientOption {
+	return func(c *Client) {
+		c.serviceKey = key
+		c.setAuthHeaders()
+	}
+}
+
+// setAuthHeaders sets the default apikey and Authorization headers.
+// The apikey header carries the anon key, falling back to the key passed to New.
+// The bearer token is the service key when set, otherwise the same key as apikey.
+func (c *Client) setAuthHeaders() {
+	apiKey := c.apiKey
+	if c.anonKey != "" {
+		apiKey = c.anonKey
+	}
+
+	bearer := apiKey
+	if c.serviceKey != "" {
+		bearer = c.serviceKey
+	}
+
+	c.httpClient.SetHeader("apikey", apiKey)
+	c.httpClient.SetHeader("Authorization", fmt.Sprintf("Bearer %s", bearer))
+}
+
+// WithSession returns a copy of the client that sends the user's access token as the
+// bearer token, so queries run with that user's row level security policies.
+// The session token takes precedence over the service key and the apikey.
+func (c *Client) WithSession(accessToken string) *Client {
+	clone := *c
+	clone.session = accessToken
+	return &clone
+}
+
+// WithDefaultSelect returns a copy of the client whose queries on table select only the
+// given columns unless the query calls Select itself, e.g. to keep password_hash out of
+// every users query. Defaults set on the original client are kept.
+func (c *Client) WithDefaultSelect(table string, columns ...string) *Client {
+	clone := *c
+	clone.defaultSelects = make(map[string][]string, len(c.defaultSelects)+1)
+	for name, cols := range c.defaultSelects {
+		clone.defaultSelects[name] = cols
+	}
+	clone.defaultSelects[table] = append([]string(nil), columns...)
+	return &clone
+}
+
+// WithTableDefaults returns a copy of the client that sends headers with every query
+// on table, e.g. Prefer: count=exact for a dashboard table. A query overrides a default
+// by setting the same header, while Prefer directives added by builder methods are
+// combined with the default. Defaults set on the original client for other tables are
+// kept; those for table are replaced.
+func (c *Client) WithTableDefaults(table string, headers map[string]string) *Client {
+	clone := *c
+	clone.tableHeaders = make(map[string]map[string]string, len(c.tableHeaders)+1)
+	for name, h := range c.tableHeaders {
+		clone.tableHeaders[name] = h
+	}
+	clone.tableHeaders[table] = make(map[string]string, len(headers))
+	for key, value := range headers {
+		clone.tableHeaders[table][key] = value
+	}
+	return &clone
+}
+
+// WithMaxLimit returns a copy of the client whose reads return at most n rows:
+// a larger Limit is clamped to n, and queries without a Limit get n.
+// Use WithStrictMaxLimit to reject larger limits instead of clamping them.
+func (c *Client) WithMaxLimit(n int) *Client {
+	clone := *c
+	clone.maxLimit = n
+	clone.maxLimitStrict = false
+	return &clone
+}
+
+// WithStrictMaxLimit is like WithMaxLimit, but a read with a Limit above n
+// fails validation with ErrLimitExceeded
+func (c *Client) WithStrictMaxLimit(n int) *Client {
+	clone := c.WithMaxLimit(n)
+	clone.maxLimitStrict = true
+	return clone
+}
+
+// normalizeBaseURL trims surrounding whitespace and trailing slashes from baseURL
+// and checks that it is an absolute http or https URL
+func normalizeBaseURL(baseURL string) (string, error) {
+	normalized := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+
+	parsed, err := url.Parse(normalized)
+	if err != nil {
+		return normalized, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return normalized, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	if parsed.Host == "" {
+		return normalized, fmt.Errorf("invalid base URL %q: missing host", baseURL)
+	}
+
+	return normalized, nil
+}
+
+// NewClientValidated is like New, but returns an error when baseURL isn't
+// an absolute http or https URL instead of failing on the first request
+func NewClientValidated(baseURL, apiKey string, options ...ClientOption) (*Client, error) {
+	if _, err := normalizeBaseURL(baseURL); err != nil {
+		return nil, err
+	}
+	return New(baseURL, apiKey, options...), nil
+}
+
+// WithDefaultOrder returns a copy of the client whose reads are ordered by column and
+// direction (e.g. "id", "asc") unless the query calls Order, so paginated results don't
+// repeat or skip rows. Order by a unique column to make pagination deterministic.
+func (c *Client) WithDefaultOrder(column, direction string) *Client {
+	clone := *c
+	clone.defaultOrder = column + "." + direction
+	return &clone
+}
+
+// New creates a new Supabase client.
+// Trailing slashes are removed from baseURL; use NewClientValidated to also check it.
 func New(baseURL, apiKey string, options ...ClientOption) *Client {
 	httpClient := resty.New()
 
+	// An invalid URL is kept as given, so the error surfaces on the first request
+	baseURL, _ = normalizeBaseURL(baseURL)
+
 	client := &Client{
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		httpClient: httpClient,
+		baseURL:      baseURL,
+		apiKey:       apiKey,
+		tableSchemas: &schemaCache{},
+		serverInfo:   &serverInfoCache{},
+		httpClient:   httpClient,
 	}
 
 	// Set default headers
-	client.httpClient.SetHeader("apikey", apiKey)
-	client.httpClient.SetHeader("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	client.setAuthHeaders()
 	client.httpClient.SetHeader("Content-Type", "application/json")
 
 	// Apply options
@@ -61,11 +346,42 @@ func New(baseURL, apiKey string, options ...ClientOption) *Client {
 
 // Table returns a new query builder for the specified table
 func (c *Client) Table(tableName string) *QueryBuilder {
-	return &QueryBuilder{
+	builder := &QueryBuilder{
 		client: c,
 		table:  tableName,
 		method: http.MethodGet,
 	}
+
+	// Apply the table's default projection; an explicit Select replaces it
+	if columns, ok := c.defaultSelects[tableName]; ok {
+		builder.selectQuery = strings.Join(columns, ",")
+	}
+
+	// Apply the table's default headers; headers set on the query replace them
+	for key, value := range c.tableHeaders[tableName] {
+		builder.Header(key, value)
+	}
+
+	return builder
+}
+
+// Schema returns a copy of the client that targets the given database schema
+// for table queries and RPC calls, e.g. client.Schema("analytics").RPC("rollup", params, &out).
+// The schema must be exposed in the PostgREST configuration.
+func (c *Client) Schema(schema string) *Client {
+	clone := *c
+	clone.schema = schema
+	return &clone
+}
+
+// FromView returns a read-only query builder for a view or materialized view.
+// Insert, Update and Delete fail with ErrReadOnlyView unless AllowWrites is called
+// for an updatable view. Materialized views are refreshed server-side, e.g. through
+// an RPC function that runs REFRESH MATERIALIZED VIEW.
+func (c *Client) FromView(name string) *QueryBuilder {
+	builder := c.Table(name)
+	builder.readOnly = true
+	return builder
 }
 
 // Auth returns the Auth instance for authentication operations
@@ -74,16 +390,86 @@ func (c *Client) Auth() *Auth {
 }
 
 // RawRequest allows making raw HTTP requests to the Supabase API
+// A session set with WithSession overrides the default bearer token.
 func (c *Client) RawRequest() *resty.Request {
-	return c.httpClient.R()
+	req := c.httpClient.R()
+	if c.session != "" {
+		req.SetHeader("Authorization", fmt.Sprintf("Bearer %s", c.session))
+	}
+	return req
 }
 
-// GetBaseURL returns the base URL of the Supabase API
+// Do sends a request to an arbitrary Supabase endpoint, for APIs the ORM doesn't cover yet.
+// The path is relative to the base URL (e.g. "/functions/v1/hello"); body is encoded as JSON
+// and the response is decoded into result when both are non-nil. Error responses are
+// returned as *APIError.
+func (c *Client) Do(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req := c.RawRequest().SetContext(ctx)
+	if body != nil {
+		req.SetBody(body)
+	}
+
+	resp, err := req.Execute(method, c.GetBaseURL()+path)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return newAPIError(resp)
+	}
+
+	if resp.StatusCode() == http.StatusNotModified {
+		return ErrNotModified
+	}
+
+	if result != nil && len(resp.Body()) > 0 {
+		return json.Unmarshal(resp.Body(), result)
+	}
+
+	return nil
+}
+
+// GetBaseURL returns the base URL of the Supabase API, without a trailing slash
 func (c *Client) GetBaseURL() string {
 	return c.baseURL
 }
 
+// SetBaseURL points the client at another Supabase API, e.g. a test server or a URL
+// discovered at runtime. The URL is normalized like in New and validated like in
+// NewClientValidated. Like options, it must not be called while the client is in use
+// by other goroutines, and copies made with WithSession or Schema keep their URL.
+// The cached ServerInfo is dropped, since the new URL may run another version.
+func (c *Client) SetBaseURL(baseURL string) error {
+	normalized, err := normalizeBaseURL(baseURL)
+	if err != nil {
+		return err
+	}
+	c.baseURL = normalized
+	// Copies keep the cache that matches their URL
+	if c.serverInfo == nil || !c.serverInfo.assumed {
+		c.serverInfo = &serverInfoCache{}
+	}
+	return nil
+}
+
 // GetAPIKey returns the API key used for authentication
 func (c *Client) GetAPIKey() string {
 	return c.apiKey
 }
+
+// Close releases the client's resources on shutdown: it closes the idle pooled
+// connections, ending their transport goroutines, and drops the responses held by
+// WithQueryCache. Copies of the client share its connections, so Close is meant for
+// the end of the process or test; a request made afterwards opens a new connection.
+// The client runs no background refresh or subscriptions, so nothing else is left.
+func (c *Client) Close() error {
+	c.httpClient.GetClient().CloseIdleConnections()
+	if c.queryCache != nil {
+		c.queryCache.clear()
+	}
+	return nil
+}
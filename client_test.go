@@ -1,6 +1,19 @@
 package supabaseorm
 
 import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -71,3 +84,753 @@ func TestTable(t *testing.T) {
 		t.Error("Expected client to be the same instance")
 	}
 }
+
+func TestDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/functions/v1/hello" && r.Method == http.MethodPost:
+			if r.Header.Get("apikey") != "test-api-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"message":"hello ` + body["name"] + `"}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"PGRST000","message":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-api-key")
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	err := client.Do(context.Background(), http.MethodPost, "functions/v1/hello", map[string]string{"name": "world"}, &result)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if result.Message != "hello world" {
+		t.Errorf("Do() result = %q, want %q", result.Message, "hello world")
+	}
+
+	err = client.Do(context.Background(), http.MethodGet, "/missing", nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Do() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "PGRST000" {
+		t.Errorf("Do() error = %+v, want 404 PGRST000", apiErr)
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Write([]byte(`[{"id":2,"name":"plain"}]`))
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`[{"id":1,"name":"compressed"}]`))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		options  []ClientOption
+		expected string
+	}{
+		{
+			name:     "enabled by default",
+			expected: "compressed",
+		},
+		{
+			name:     "disabled",
+			options:  []ClientOption{WithCompression(false)},
+			expected: "plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New(server.URL, "test-api-key", tt.options...)
+
+			var rows []struct {
+				Name string `json:"name"`
+			}
+			if err := client.Do(context.Background(), http.MethodGet, "/rest/v1/users", nil, &rows); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+
+			if len(rows) != 1 || rows[0].Name != tt.expected {
+				t.Errorf("rows = %v, want name %q", rows, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWithRequestCompression(t *testing.T) {
+	var encoding string
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+
+		var body io.Reader = r.Body
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+
+		received = nil
+		if err := json.NewDecoder(body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-api-key", WithRequestCompression())
+
+	rows := make([]map[string]interface{}, 100)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"name": strings.Repeat("x", 20)}
+	}
+
+	if err := client.Table("users").Insert(rows); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if encoding != "gzip" || len(received) != len(rows) {
+		t.Errorf("large insert: encoding = %q, rows = %d, want gzip and %d rows", encoding, len(received), len(rows))
+	}
+
+	if err := client.Table("users").Insert(rows[:1]); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if encoding != "" || len(received) != 1 {
+		t.Errorf("small insert: encoding = %q, rows = %d, want uncompressed single row", encoding, len(received))
+	}
+}
+
+func TestWithRequestCompressionRetry(t *testing.T) {
+	attempts := 0
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		// A body compressed once per attempt would decode to gzip bytes, not JSON
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(gz).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-api-key", WithRequestCompression(), WithRetry(2, time.Millisecond))
+
+	// Random names keep the compressed body above the threshold
+	rows := make([]map[string]interface{}, 100)
+	for i := range rows {
+		name := make([]byte, 16)
+		rand.Read(name)
+		rows[i] = map[string]interface{}{"name": hex.EncodeToString(name)}
+	}
+
+	if err := client.Table("users").IdempotencyKey("batch-1").Insert(rows); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if attempts != 2 || len(received) != len(rows) {
+		t.Errorf("attempts = %d, rows = %d, want 2 attempts and %d rows", attempts, len(received), len(rows))
+	}
+}
+
+func TestFromView(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-api-key")
+
+	var rows []map[string]interface{}
+	if err := client.FromView("active_users").Get(&rows); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	err := client.FromView("active_users").Insert(map[string]interface{}{"name": "John"})
+	if !errors.Is(err, ErrReadOnlyView) {
+		t.Errorf("Insert() error = %v, want ErrReadOnlyView", err)
+	}
+
+	err = client.FromView("active_users").Where("id", "eq", 1).Delete()
+	if !errors.Is(err, ErrReadOnlyView) {
+		t.Errorf("Delete() error = %v, want ErrReadOnlyView", err)
+	}
+
+	err = client.FromView("active_users").AllowWrites().Where("id", "eq", 1).Update(map[string]interface{}{"name": "Jane"})
+	if err != nil {
+		t.Errorf("Update() with AllowWrites error = %v", err)
+	}
+
+	expected := []string{http.MethodGet, http.MethodPatch}
+	if strings.Join(methods, ",") != strings.Join(expected, ",") {
+		t.Errorf("sent %v, want %v", methods, expected)
+	}
+}
+
+func TestAuthHeaderPrecedence(t *testing.T) {
+	var apiKey, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("apikey")
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		client         func() *Client
+		expectedAPIKey string
+		expectedBearer string
+	}{
+		{
+			name: "anon only",
+			client: func() *Client {
+				return New(server.URL, "anon-key")
+			},
+			expectedAPIKey: "anon-key",
+			expectedBearer: "Bearer anon-key",
+		},
+		{
+			name: "service role",
+			client: func() *Client {
+				return New(server.URL, "anon-key", WithServiceKey("service-key"))
+			},
+			expectedAPIKey: "anon-key",
+			expectedBearer: "Bearer service-key",
+		},
+		{
+			name: "explicit anon key",
+			client: func() *Client {
+				return New(server.URL, "service-key", WithAnonKey("anon-key"))
+			},
+			expectedAPIKey: "anon-key",
+			expectedBearer: "Bearer anon-key",
+		},
+		{
+			name: "anon key before service key",
+			client: func() *Client {
+				return New(server.URL, "other-key", WithAnonKey("anon-key"), WithServiceKey("service-key"))
+			},
+			expectedAPIKey: "anon-key",
+			expectedBearer: "Bearer service-key",
+		},
+		{
+			name: "anon key after service key",
+			client: func() *Client {
+				return New(server.URL, "other-key", WithServiceKey("service-key"), WithAnonKey("anon-key"))
+			},
+			expectedAPIKey: "anon-key",
+			expectedBearer: "Bearer service-key",
+		},
+		{
+			name: "user session",
+			client: func() *Client {
+				return New(server.URL, "anon-key", WithServiceKey("service-key")).WithSession("user-jwt")
+			},
+			expectedAPIKey: "anon-key",
+			expectedBearer: "Bearer user-jwt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []map[string]interface{}
+			if err := tt.client().Table("users").Get(&rows); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if apiKey != tt.expectedAPIKey || authorization != tt.expectedBearer {
+				t.Errorf("headers = %q/%q, want %q/%q", apiKey, authorization, tt.expectedAPIKey, tt.expectedBearer)
+			}
+		})
+	}
+}
+
+// TestConcurrentUse shares one client, along with the state its copies share (circuit
+// breaker, query cache, server info), across goroutines; run it with -race.
+func TestConcurrentUse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/rest/v1/":
+			w.Header().Set("Server", "postgrest/12.0.2")
+			w.Write([]byte(`{"swagger":"2.0"}`))
+		case r.URL.Path == "/rest/v1/users":
+			id := strings.TrimPrefix(r.URL.Query().Get("id"), "eq.")
+			w.Write([]byte(`[{"id":` + id + `}]`))
+		case r.URL.Path == "/rest/v1/rpc/echo":
+			io.Copy(w, r.Body)
+		case r.URL.Path == "/auth/v1/user":
+			w.Write([]byte(`{"id":"` + strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-api-key", WithCircuitBreaker(5, time.Second))
+	cached := client.WithQueryCache(time.Minute, 10)
+
+	const workers = 100
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			var rows []struct {
+				ID int `json:"id"`
+			}
+			if err := client.From("users").Select("id").Where("id", "eq", i).Get(&rows); err != nil {
+				errs <- err
+				return
+			}
+			if len(rows) != 1 || rows[0].ID != i {
+				errs <- fmt.Errorf("worker %d: got rows %+v", i, rows)
+				return
+			}
+
+			if err := cached.From("users").Where("id", "eq", i%3).Get(&rows); err != nil {
+				errs <- err
+				return
+			}
+			if len(rows) != 1 || rows[0].ID != i%3 {
+				errs <- fmt.Errorf("worker %d: got cached rows %+v", i, rows)
+				return
+			}
+
+			if info, err := client.Schema("analytics").ServerInfo(context.Background()); err != nil || info.Major != 12 {
+				errs <- fmt.Errorf("worker %d: ServerInfo() = %v, %v", i, info, err)
+				return
+			}
+
+			var echoed map[string]int
+			if err := client.RPC("echo", map[string]interface{}{"n": i}, &echoed); err != nil {
+				errs <- err
+				return
+			}
+			if echoed["n"] != i {
+				errs <- fmt.Errorf("worker %d: RPC echoed %v", i, echoed)
+				return
+			}
+
+			token := fmt.Sprintf("token-%d", i)
+			user, err := client.WithSession(token).Auth().GetUser(context.Background(), token)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if user.ID != token {
+				errs <- fmt.Errorf("worker %d: got user %q", i, user.ID)
+				return
+			}
+			errs <- nil
+		}(i)
+	}
+
+	for i := 0; i < workers; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWithDefaultSelect(t *testing.T) {
+	base := New("https://example.supabase.co", "test-api-key")
+	client := base.WithDefaultSelect("users", "id", "name", "email")
+
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{"default applied", client.From("users"), "/users?select=id,name,email"},
+		{"explicit select overrides", client.From("users").Select("id"), "/users?select=id"},
+		{"other tables unaffected", client.From("posts"), "/posts"},
+		{"original client unaffected", base.From("users"), "/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.builder.BuildURL(); got != tt.expected {
+				t.Errorf("BuildURL() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWithTableDefaults(t *testing.T) {
+	var prefer, source string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		source = r.Header.Get("X-Source")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	base := New(server.URL, "test-api-key")
+	client := base.WithTableDefaults("metrics", map[string]string{
+		"Prefer":   "count=estimated",
+		"X-Source": "dashboard",
+	})
+
+	tests := []struct {
+		name       string
+		builder    *QueryBuilder
+		wantPrefer string
+		wantSource string
+	}{
+		{"default applied", client.From("metrics"), "count=estimated", "dashboard"},
+		{"query overrides", client.From("metrics").Header("X-Source", "export"), "count=estimated", "export"},
+		{"prefer overridden", client.From("metrics").Header("Prefer", "count=exact"), "count=exact", "dashboard"},
+		{"other tables unaffected", client.From("users"), "", ""},
+		{"original client unaffected", base.From("metrics"), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []map[string]interface{}
+			if err := tt.builder.Get(&rows); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if prefer != tt.wantPrefer || source != tt.wantSource {
+				t.Errorf("Prefer, X-Source = %q, %q, want %q, %q", prefer, source, tt.wantPrefer, tt.wantSource)
+			}
+		})
+	}
+
+	// Directives added by builder methods are combined with the default
+	row := map[string]interface{}{"id": 1, "value": 42}
+	if err := client.From("metrics").Upsert(&row); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if prefer != "count=estimated, resolution=merge-duplicates, return=representation" {
+		t.Errorf("Prefer = %q, want the default combined with the upsert directives", prefer)
+	}
+}
+
+func TestWithMaxLimit(t *testing.T) {
+	var limit string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit = r.URL.Query().Get("limit")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-api-key").WithMaxLimit(100)
+
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{"default applied", client.From("users"), "100"},
+		{"limit clamped", client.From("users").Limit(1000000), "100"},
+		{"smaller limit kept", client.From("users").Limit(10), "10"},
+		{"unrestricted client", New(server.URL, "test-api-key").From("users"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []map[string]interface{}
+			if err := tt.builder.Get(&rows); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if limit != tt.expected {
+				t.Errorf("limit = %q, want %q", limit, tt.expected)
+			}
+		})
+	}
+
+	strict := New(server.URL, "test-api-key").WithStrictMaxLimit(100)
+
+	var rows []map[string]interface{}
+	if err := strict.From("users").Limit(1000).Get(&rows); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Get() error = %v, want ErrLimitExceeded", err)
+	}
+
+	if err := strict.From("users").Get(&rows); err != nil || limit != "100" {
+		t.Errorf("Get() error = %v, limit = %q, want default limit 100", err, limit)
+	}
+}
+
+func TestBaseURLNormalization(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		expected string
+		wantErr  bool
+	}{
+		{"valid", "https://example.supabase.co", "https://example.supabase.co", false},
+		{"trailing slash", "https://example.supabase.co/", "https://example.supabase.co", false},
+		{"several trailing slashes and spaces", " https://example.supabase.co// ", "https://example.supabase.co", false},
+		{"local http with port", "http://localhost:54321/", "http://localhost:54321", false},
+		{"missing scheme", "example.supabase.co", "", true},
+		{"unsupported scheme", "ftp://example.supabase.co", "", true},
+		{"missing host", "https://", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientValidated(tt.baseURL, "test-api-key")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClientValidated() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := client.GetBaseURL(); got != tt.expected {
+				t.Errorf("GetBaseURL() = %q, want %q", got, tt.expected)
+			}
+			if got := New(tt.baseURL, "test-api-key").GetBaseURL(); got != tt.expected {
+				t.Errorf("New().GetBaseURL() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetBaseURL(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"name":"` + name + `"}]`))
+		}))
+	}
+	first, second := newServer("first"), newServer("second")
+	defer first.Close()
+	defer second.Close()
+
+	client := New(first.URL, "test-api-key")
+
+	query := func() string {
+		var rows []TestUser
+		if err := client.Table("users").Get(&rows); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return rows[0].Name
+	}
+
+	if got := query(); got != "first" {
+		t.Errorf("query before SetBaseURL reached %q, want first", got)
+	}
+
+	if err := client.SetBaseURL(second.URL + "/"); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	if client.GetBaseURL() != second.URL {
+		t.Errorf("GetBaseURL() = %q, want %q", client.GetBaseURL(), second.URL)
+	}
+	if got := query(); got != "second" {
+		t.Errorf("query after SetBaseURL reached %q, want second", got)
+	}
+
+	if err := client.SetBaseURL("not a url"); err == nil {
+		t.Error("SetBaseURL() with invalid URL error = nil, want error")
+	}
+	if client.GetBaseURL() != second.URL {
+		t.Errorf("GetBaseURL() after failed SetBaseURL = %q, want %q", client.GetBaseURL(), second.URL)
+	}
+}
+
+func TestWithDefaultOrder(t *testing.T) {
+	var order string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = r.URL.Query().Get("order")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Range", "0-0/1")
+		w.Write([]byte(`[{"max":42}]`))
+	}))
+	defer server.Close()
+
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	base := New(server.URL, "test-api-key", WithLogger(logger))
+	client := base.WithDefaultOrder("id", "asc")
+
+	var rows []map[string]interface{}
+	if err := client.From("users").Range(0, 9).Get(&rows); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if order != "id.asc" {
+		t.Errorf("order = %q, want default id.asc", order)
+	}
+
+	if err := client.From("users").Order("created_at", "desc").Get(&rows); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if order != "created_at.desc" {
+		t.Errorf("order = %q, want explicit created_at.desc", order)
+	}
+
+	if err := client.From("users").Where("id", "eq", 1).Delete(); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if order != "" {
+		t.Errorf("order = %q, want none on writes", order)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("logged %q, want nothing with a default order", logs.String())
+	}
+
+	// Grouped and counted reads can't be ordered by an ungrouped column
+	var maxAge int
+	if err := client.From("users").Select("age.max()").GetScalar(&maxAge); err != nil {
+		t.Fatalf("GetScalar() error = %v", err)
+	}
+	if order != "" {
+		t.Errorf("order = %q, want none on scalar reads", order)
+	}
+	if err := client.From("users").Select("role", "total:count()").Get(&rows); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if order != "" {
+		t.Errorf("order = %q, want none on aggregates", order)
+	}
+	if _, err := client.From("users").CountContext(context.Background()); err != nil {
+		t.Fatalf("CountContext() error = %v", err)
+	}
+	if order != "" {
+		t.Errorf("order = %q, want none on counts", order)
+	}
+	if err := client.From("users").Select("id", "posts(amount.sum())").Get(&rows); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if order != "id.asc" {
+		t.Errorf("order = %q, want default id.asc with aggregates only in an embed", order)
+	}
+
+	if err := base.From("users").Offset(20).Get(&rows); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "paginating without an order") {
+		t.Errorf("logs = %q, want a pagination warning", logs.String())
+	}
+}
+
+func TestWithTransportConfig(t *testing.T) {
+	client := New("http://localhost:54321", "test-api-key", WithTransportConfig(100, 50, 90*time.Second))
+
+	transport, err := client.httpClient.Transport()
+	if err != nil {
+		t.Fatalf("Transport() error = %v", err)
+	}
+
+	if transport.MaxIdleConns != 100 || transport.MaxIdleConnsPerHost != 100 {
+		t.Errorf("idle connections = %d, %d per host, want 100", transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	}
+	if transport.MaxConnsPerHost != 50 {
+		t.Errorf("MaxConnsPerHost = %d, want 50", transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 90*time.Second {
+		t.Errorf("IdleConnTimeout = %v, want 90s", transport.IdleConnTimeout)
+	}
+}
+
+func BenchmarkTransportConfig(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":1}]`))
+	}))
+	defer server.Close()
+
+	benchmarks := []struct {
+		name    string
+		options []ClientOption
+	}{
+		{"default", nil},
+		{"pooled", []ClientOption{WithTransportConfig(64, 0, 90*time.Second)}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			client := New(server.URL, "test-api-key", bm.options...)
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					var rows []map[string]interface{}
+					if err := client.Table("users").Get(&rows); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":1}]`))
+	}))
+	defer server.Close()
+
+	before := runtime.NumGoroutine()
+
+	client := New(server.URL, "test-api-key").WithQueryCache(time.Minute, 10)
+	for i := 0; i < 3; i++ {
+		var rows []map[string]interface{}
+		if err := client.Table("users").Where("id", "eq", i).Get(&rows); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if runtime.NumGoroutine() <= before {
+		t.Fatal("expected pooled connections to hold goroutines before Close")
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// The connection goroutines exit asynchronously once their connection is closed
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("goroutines after Close = %d, want at most %d", n, before)
+	}
+
+	if len(client.queryCache.entries) != 0 {
+		t.Errorf("cached responses after Close = %d, want 0", len(client.queryCache.entries))
+	}
+}
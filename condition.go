@@ -0,0 +1,119 @@
+package supabaseorm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Condition is a typed filter, e.g. Condition{Column: "age", Operator: "gte", Value: 18},
+// for building queries from metadata instead of chained Where calls
+type Condition struct {
+	Column   string
+	Operator string
+	Value    interface{}
+}
+
+// term returns the condition in the form used inside logical groups, e.g. age.gte.18.
+// A nil value is written as null, and string values with reserved characters are
+// quoted, so commas and parentheses in them don't split the group.
+func (c Condition) term() string {
+	if c.Value == nil {
+		return fmt.Sprintf("%s.%s.null", quoteColumn(c.Column), c.Operator)
+	}
+
+	value := formatOperand(c.Operator, c.Value)
+	if s, ok := c.Value.(string); ok {
+		value = formatListItem(s)
+	}
+	return fmt.Sprintf("%s.%s.%s", quoteColumn(c.Column), c.Operator, value)
+}
+
+// conditionExprs converts conds to expressions for a logical group
+func conditionExprs(conds []Condition) []Expr {
+	exprs := make([]Expr, len(conds))
+	for i, c := range conds {
+		exprs[i] = c
+	}
+	return exprs
+}
+
+// Expr is a filter expression that nests in logical groups: a Condition, or a group
+// built with AllOf and AnyOf. Add it to a query with WhereExpr.
+type Expr interface {
+	term() string
+}
+
+// logicalGroup is an AND or OR group of expressions
+type logicalGroup struct {
+	operator string
+	exprs    []Expr
+}
+
+// AllOf groups expressions that must all hold, e.g. and(a.eq.1,b.eq.2)
+func AllOf(exprs ...Expr) Expr {
+	return logicalGroup{operator: "and", exprs: exprs}
+}
+
+// AnyOf groups expressions of which at least one must hold, e.g. or(a.eq.1,b.eq.2)
+func AnyOf(exprs ...Expr) Expr {
+	return logicalGroup{operator: "or", exprs: exprs}
+}
+
+// terms returns the terms of the group's expressions
+func (g logicalGroup) terms() string {
+	terms := make([]string, len(g.exprs))
+	for i, e := range g.exprs {
+		terms[i] = e.term()
+	}
+	return strings.Join(terms, ",")
+}
+
+// term returns the group nested in another group, e.g. and(a.eq.1,b.eq.2)
+func (g logicalGroup) term() string {
+	return g.operator + "(" + g.terms() + ")"
+}
+
+// WhereExpr adds an expression that may nest groups, e.g. (A AND B) OR (C AND D):
+//
+//	q.WhereExpr(AnyOf(
+//		AllOf(
+//			Condition{Column: "status", Operator: "eq", Value: "active"},
+//			Condition{Column: "age", Operator: "gte", Value: 18},
+//		),
+//		AllOf(
+//			Condition{Column: "role", Operator: "eq", Value: "admin"},
+//			Condition{Column: "verified", Operator: "is", Value: true},
+//		),
+//	))
+//
+// emits or=(and(status.eq.active,age.gte.18),and(role.eq.admin,verified.is.true)).
+// The expression is ANDed with the query's other filters.
+func (q *QueryBuilder) WhereExpr(e Expr) *QueryBuilder {
+	switch e := e.(type) {
+	case Condition:
+		return q.Where(e.Column, e.Operator, e.Value)
+	case logicalGroup:
+		if len(e.exprs) == 0 {
+			return q
+		}
+		filter := e.operator + "=(" + e.terms() + ")"
+		if e.operator == "or" {
+			q.orFilters = append(q.orFilters, filter)
+		} else {
+			q.andFilters = append(q.andFilters, filter)
+		}
+	}
+	return q
+}
+
+// WhereAll adds a group matching rows that satisfy every condition, e.g.
+// and=(age.gte.18,status.eq.active)
+func (q *QueryBuilder) WhereAll(conds ...Condition) *QueryBuilder {
+	return q.WhereExpr(AllOf(conditionExprs(conds)...))
+}
+
+// WhereAnyOf adds a group matching rows that satisfy at least one condition, e.g.
+// or=(role.eq.admin,role.eq.owner)
+func (q *QueryBuilder) WhereAnyOf(conds ...Condition) *QueryBuilder {
+	return q.WhereExpr(AnyOf(conditionExprs(conds)...))
+}
@@ -0,0 +1,89 @@
+package supabaseorm
+
+import "testing"
+
+func TestWhereAllAndAnyOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{
+			"all",
+			NewQueryBuilder("users").WhereAll(
+				Condition{Column: "age", Operator: "gte", Value: 18.5},
+				Condition{Column: "status", Operator: "eq", Value: "active"},
+			),
+			"/users?and=(age.gte.18.5,status.eq.active)",
+		},
+		{
+			"any of",
+			NewQueryBuilder("users").WhereAnyOf(
+				Condition{Column: "role", Operator: "eq", Value: "admin"},
+				Condition{Column: "role", Operator: "in", Value: []string{"owner", "editor"}},
+			),
+			"/users?or=(role.eq.admin,role.in.(owner,editor))",
+		},
+		{
+			"quoted value",
+			NewQueryBuilder("users").WhereAnyOf(
+				Condition{Column: "name", Operator: "eq", Value: "Doe, John"},
+				Condition{Column: "name", Operator: "is", Value: nil},
+			),
+			`/users?or=(name.eq."Doe, John",name.is.null)`,
+		},
+		{
+			"empty",
+			NewQueryBuilder("users").WhereAll().WhereAnyOf(),
+			"/users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if url := tt.builder.BuildURL(); url != tt.expected {
+				t.Errorf("BuildURL() = %q, want %q", url, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWhereExpr(t *testing.T) {
+	q := NewQueryBuilder("users").
+		Where("deleted_at", "is", "null").
+		WhereExpr(AnyOf(
+			AllOf(
+				Condition{Column: "status", Operator: "eq", Value: "active"},
+				Condition{Column: "age", Operator: "gte", Value: 18},
+			),
+			AllOf(
+				Condition{Column: "role", Operator: "eq", Value: "admin"},
+				AnyOf(
+					Condition{Column: "team", Operator: "eq", Value: "core"},
+					Condition{Column: "team", Operator: "eq", Value: "ops"},
+				),
+			),
+		))
+
+	if errs := q.Validate(); len(errs) != 0 {
+		t.Fatalf("Validate() = %v", errs)
+	}
+
+	expected := "/users?deleted_at=is.null&or=(and(status.eq.active,age.gte.18),and(role.eq.admin,or(team.eq.core,team.eq.ops)))"
+	if url := q.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
+	}
+
+	single := NewQueryBuilder("users").WhereExpr(Condition{Column: "age", Operator: "gt", Value: 30})
+	if url := single.BuildURL(); url != "/users?age=gt.30" {
+		t.Errorf("BuildURL() for a condition = %q", url)
+	}
+
+	top := NewQueryBuilder("users").WhereExpr(AllOf(
+		Condition{Column: "a", Operator: "eq", Value: 1},
+		AnyOf(Condition{Column: "b", Operator: "eq", Value: 2}, Condition{Column: "c", Operator: "eq", Value: 3}),
+	))
+	if url := top.BuildURL(); url != "/users?and=(a.eq.1,or(b.eq.2,c.eq.3))" {
+		t.Errorf("BuildURL() for an and group = %q", url)
+	}
+}
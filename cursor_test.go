@@ -0,0 +1,69 @@
+package supabaseorm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	values := map[string]interface{}{
+		"created_at": "2024-03-01T12:00:00Z",
+		"id":         int64(9007199254740993),
+	}
+
+	for _, codec := range []*CursorCodec{NewCursorCodec(nil), NewCursorCodec([]byte("s3cret"))} {
+		cursor, err := codec.Encode(values)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		if strings.ContainsAny(cursor, "+/=") {
+			t.Errorf("cursor %q is not URL-safe", cursor)
+		}
+
+		decoded, err := codec.Decode(cursor)
+		if err != nil {
+			t.Fatalf("Decode(%q) error = %v", cursor, err)
+		}
+		if decoded["created_at"] != "2024-03-01T12:00:00Z" || decoded["id"] != json.Number("9007199254740993") {
+			t.Errorf("Decode() = %v, want %v", decoded, values)
+		}
+	}
+
+	decoded, err := DecodeCursor(EncodeCursor(map[string]interface{}{"id": 7}))
+	if err != nil || decoded["id"] != json.Number("7") {
+		t.Errorf("DecodeCursor(EncodeCursor()) = %v, %v", decoded, err)
+	}
+}
+
+func TestCursorTampered(t *testing.T) {
+	codec := NewCursorCodec([]byte("s3cret"))
+	cursor, _ := codec.Encode(map[string]interface{}{"id": 10})
+	payload, signature, _ := strings.Cut(cursor, ".")
+
+	forged := EncodeCursor(map[string]interface{}{"id": 1000})
+	otherSecret, _ := NewCursorCodec([]byte("other")).Encode(map[string]interface{}{"id": 10})
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"payload changed", forged + "." + signature},
+		{"signature changed", payload + "." + strings.Repeat("A", len(signature))},
+		{"unsigned", payload},
+		{"other secret", otherSecret},
+		{"garbage", "not a cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.Decode(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Decode() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	// Unsigned decoding rejects signed cursors rather than ignoring the signature
+	if _, err := DecodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("DecodeCursor() of a signed cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
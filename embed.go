@@ -0,0 +1,213 @@
+package supabaseorm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EmbedBuilder shapes an embedded resource: the columns selected from it and the
+// filters, order and limit applied to the embedded rows only, e.g.
+//
+//	q.Embed("posts").Select("id", "title").Where("published", "eq", true)
+//
+// adds posts(id,title) to the select and posts.published=eq.true to the query.
+// Embed filters don't remove parent rows; they only narrow the embedded list.
+// Embeds nest to any depth, see EmbedBuilder.Embed. The embedded rows decode into the
+// struct field named like the embed, e.g. Author Author `json:"author"` for a to-one
+// embed, which may be a pointer to allow null, or Posts []Post `json:"posts"`.
+type EmbedBuilder struct {
+	parent *QueryBuilder
+	// outer is the embed this one is nested in, or nil at the top level
+	outer    *EmbedBuilder
+	children []*EmbedBuilder
+	table    string
+	columns  []string
+	filters  []string
+	order    string
+	limit    string
+	// absent selects no columns and keeps only parents without embedded rows, see WhereDoesntHave
+	absent bool
+}
+
+// Embed adds an embedded resource for table to the query and returns its builder.
+// Use End to continue with the parent query.
+func (q *QueryBuilder) Embed(table string) *EmbedBuilder {
+	embed := &EmbedBuilder{parent: q, table: table}
+	q.embedBuilders = append(q.embedBuilders, embed)
+	return embed
+}
+
+// WhereDoesntHave keeps the parent rows that have no rows in foreignTable matching the
+// conditions set by fn, e.g. users without a published post:
+//
+//	q.WhereDoesntHave("posts", func(e *EmbedBuilder) {
+//		e.Where("published", "eq", true)
+//	})
+//
+// PostgREST has no anti-join, so the relationship is embedded without columns, the
+// conditions narrow the embedded rows, and a null filter on the embed drops the parents
+// that still have some: select=*,posts()&posts.published=eq.true&posts=is.null.
+// fn may be nil to match parents without any rows. Requires PostgREST 11.
+func (q *QueryBuilder) WhereDoesntHave(foreignTable string, fn func(*EmbedBuilder)) *QueryBuilder {
+	if !identifierPattern.MatchString(foreignTable) {
+		q.errs = append(q.errs, fmt.Errorf("invalid table name %q", foreignTable))
+		return q
+	}
+
+	embed := q.Embed(foreignTable)
+	embed.absent = true
+	if fn != nil {
+		fn(embed)
+	}
+
+	q.filters = append(q.filters, quoteColumn(foreignTable)+"=is.null")
+	return q
+}
+
+// OrderForeign sorts the rows of an embedded resource within each parent row, e.g.
+// OrderForeign("posts", "created_at", "desc") emits posts.order=created_at.desc for
+// newest-first posts per user. foreignTable may be a nested path such as
+// posts.comments. An embed added with Embed keeps its limit and gets this order, as with
+// EmbedBuilder.Order; for resources embedded through Select, only the order is sent.
+func (q *QueryBuilder) OrderForeign(foreignTable, column, direction string) *QueryBuilder {
+	for _, table := range strings.Split(foreignTable, ".") {
+		if !identifierPattern.MatchString(table) {
+			q.errs = append(q.errs, fmt.Errorf("invalid foreign table %q", foreignTable))
+			return q
+		}
+	}
+
+	for _, top := range q.embedBuilders {
+		for _, e := range top.all() {
+			if e.path() == foreignTable {
+				e.Order(column, direction)
+				return q
+			}
+		}
+	}
+
+	order := fmt.Sprintf("%s.%s", quoteColumn(column), direction)
+	for i, o := range q.foreignOrders {
+		if o.key == foreignTable {
+			q.foreignOrders[i].value = order
+			return q
+		}
+	}
+	q.foreignOrders = append(q.foreignOrders, rawParam{key: foreignTable, value: order})
+	return q
+}
+
+// Select specifies the columns to return from the embedded rows
+func (e *EmbedBuilder) Select(columns ...string) *EmbedBuilder {
+	e.columns = columns
+	return e
+}
+
+// Where filters the embedded rows
+func (e *EmbedBuilder) Where(column, operator string, value interface{}) *EmbedBuilder {
+	e.filters = append(e.filters, fmt.Sprintf("%s=%s.%s", quoteColumn(column), operator, formatOperand(operator, value)))
+	return e
+}
+
+// Order sorts the embedded rows
+func (e *EmbedBuilder) Order(column, direction string) *EmbedBuilder {
+	e.order = fmt.Sprintf("%s.%s", column, direction)
+	return e
+}
+
+// Limit caps the number of embedded rows per parent row
+func (e *EmbedBuilder) Limit(limit int) *EmbedBuilder {
+	e.limit = fmt.Sprintf("%d", limit)
+	return e
+}
+
+// Embed nests an embedded resource for table in this one and returns its builder,
+// e.g. users with their posts and each post's comments:
+//
+//	q.Embed("posts").Embed("comments").Up().End()
+//
+// selects *,posts(*,comments(*)). Use Up to continue with this embed.
+func (e *EmbedBuilder) Embed(table string) *EmbedBuilder {
+	child := &EmbedBuilder{parent: e.parent, outer: e, table: table}
+	e.children = append(e.children, child)
+	return child
+}
+
+// Up returns the embed this one is nested in, or this embed at the top level
+func (e *EmbedBuilder) Up() *EmbedBuilder {
+	if e.outer == nil {
+		return e
+	}
+	return e.outer
+}
+
+// End returns the parent query
+func (e *EmbedBuilder) End() *QueryBuilder {
+	return e.parent
+}
+
+// selectClause returns the embed for the select parameter, e.g. posts(id,title),
+// with nested embeds after the columns, e.g. posts(*,comments(*))
+func (e *EmbedBuilder) selectClause() string {
+	columns := []string{"*"}
+	if e.absent {
+		columns = nil
+	} else if len(e.columns) > 0 {
+		columns = append([]string(nil), e.columns...)
+	}
+	for _, child := range e.children {
+		columns = append(columns, child.selectClause())
+	}
+	return fmt.Sprintf("%s(%s)", e.table, strings.Join(columns, ","))
+}
+
+// path returns the embed's tables from the top level, e.g. posts.comments
+func (e *EmbedBuilder) path() string {
+	if e.outer == nil {
+		return e.table
+	}
+	return e.outer.path() + "." + e.table
+}
+
+// params returns the query parameters scoped to the embed and those nested in it,
+// e.g. posts.published=eq.true and posts.comments.limit=3
+func (e *EmbedBuilder) params() []string {
+	path := e.path()
+	params := make([]string, 0, len(e.filters)+2)
+	for _, f := range e.filters {
+		params = append(params, path+"."+f)
+	}
+	if e.order != "" {
+		params = append(params, path+".order="+e.order)
+	}
+	if e.limit != "" {
+		params = append(params, path+".limit="+e.limit)
+	}
+	for _, child := range e.children {
+		params = append(params, child.params()...)
+	}
+	return params
+}
+
+// all returns the embed and every embed nested in it
+func (e *EmbedBuilder) all() []*EmbedBuilder {
+	embeds := []*EmbedBuilder{e}
+	for _, child := range e.children {
+		embeds = append(embeds, child.all()...)
+	}
+	return embeds
+}
+
+// clone copies the embed and those nested in it for a cloned parent query
+func (e *EmbedBuilder) clone(parent *QueryBuilder) *EmbedBuilder {
+	c := *e
+	c.parent = parent
+	c.columns = append([]string(nil), e.columns...)
+	c.filters = append([]string(nil), e.filters...)
+	c.children = make([]*EmbedBuilder, len(e.children))
+	for i, child := range e.children {
+		c.children[i] = child.clone(parent)
+		c.children[i].outer = &c
+	}
+	return &c
+}
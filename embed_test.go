@@ -0,0 +1,261 @@
+package supabaseorm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	qb := client.Table("users").Select("id", "name")
+	qb.Embed("posts").
+		Select("id", "title").
+		Where("published", "eq", true).
+		Order("created_at", "desc").
+		Limit(5).
+		End().
+		Where("active", "eq", true)
+
+	expected := "/users?select=id,name,posts(id,title)&active=eq.true&posts.published=eq.true&posts.order=created_at.desc&posts.limit=5"
+	if url := qb.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %v, want %v", url, expected)
+	}
+
+	var users []TestUser
+	if err := qb.Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	params := map[string]string{
+		"select":          "id,name,posts(id,title)",
+		"active":          "eq.true",
+		"posts.published": "eq.true",
+		"posts.order":     "created_at.desc",
+		"posts.limit":     "5",
+	}
+	for key, want := range params {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	// Embed filters don't count as filters on the parent rows
+	err := client.Table("users").Embed("posts").Where("published", "eq", false).End().Delete()
+	if err == nil {
+		t.Error("Delete() with only embed filters error = nil, want error")
+	}
+
+	invalid := client.Table("users").Embed("posts").Where("title", "resembles", "x").End()
+	if errs := invalid.Validate(); len(errs) != 1 {
+		t.Errorf("Validate() = %v, want one error", errs)
+	}
+}
+
+func TestNestedEmbed(t *testing.T) {
+	qb := NewQueryBuilder("users")
+	qb.Embed("posts").
+		Embed("comments").
+		Up().
+		End()
+
+	expected := "/users?select=*,posts(*,comments(*))"
+	if url := qb.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %v, want %v", url, expected)
+	}
+
+	shaped := NewQueryBuilder("users").Select("id", "name")
+	shaped.Embed("posts").
+		Select("id", "title").
+		Where("published", "eq", true).
+		Embed("comments").
+		Select("body").
+		Order("created_at", "desc").
+		Limit(3).
+		Embed("users").Select("name").Up().
+		Up().
+		Embed("tags").
+		Up().
+		End()
+
+	expected = "/users?select=id,name,posts(id,title,comments(body,users(name)),tags(*))" +
+		"&posts.published=eq.true&posts.comments.order=created_at.desc&posts.comments.limit=3"
+	if url := shaped.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %v, want %v", url, expected)
+	}
+
+	clone := shaped.clone()
+	clone.embedBuilders[0].children[0].Where("approved", "eq", true)
+	if url := shaped.BuildURL(); url != expected {
+		t.Errorf("BuildURL() after changing a clone = %v, want %v", url, expected)
+	}
+
+	invalid := NewQueryBuilder("users")
+	invalid.Embed("posts").Embed("comments").Where("body", "bogus", "x")
+	if errs := invalid.Validate(); len(errs) != 1 {
+		t.Errorf("Validate() with an invalid nested filter = %v, want one error", errs)
+	}
+}
+
+func TestWhereDoesntHave(t *testing.T) {
+	qb := NewQueryBuilder("users").WhereDoesntHave("posts", func(e *EmbedBuilder) {
+		e.Where("published", "eq", true)
+	})
+
+	expected := "/users?select=*,posts()&posts=is.null&posts.published=eq.true"
+	if url := qb.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %v, want %v", url, expected)
+	}
+	if errs := qb.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+
+	none := NewQueryBuilder("users").Select("id").WhereDoesntHave("orders", nil)
+	expected = "/users?select=id,orders()&orders=is.null"
+	if url := none.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %v, want %v", url, expected)
+	}
+
+	if errs := NewQueryBuilder("users").WhereDoesntHave("posts; drop", nil).Validate(); len(errs) != 1 {
+		t.Errorf("Validate() = %v, want one error for the table name", errs)
+	}
+
+	old := New("http://localhost:54321", "fake-api-key", WithAssumeServerVersion("10.2.0"))
+	if errs := old.Table("users").WhereDoesntHave("posts", nil).Validate(); len(errs) != 1 || !errors.Is(errs[0], ErrUnsupportedFeature) {
+		t.Errorf("Validate() on PostgREST 10 = %v, want ErrUnsupportedFeature", errs)
+	}
+}
+
+func TestOrderForeign(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{
+			"embedded through select",
+			NewQueryBuilder("users").Select("id", "posts(id,title)").OrderForeign("posts", "created_at", "desc"),
+			"/users?select=id,posts(id,title)&posts.order=created_at.desc",
+		},
+		{
+			"embed with limit",
+			NewQueryBuilder("users").Embed("posts").Limit(5).End().OrderForeign("posts", "created_at", "desc"),
+			"/users?select=*,posts(*)&posts.order=created_at.desc&posts.limit=5",
+		},
+		{
+			"nested path",
+			NewQueryBuilder("users").Embed("posts").Embed("comments").Limit(3).Up().End().
+				OrderForeign("posts.comments", "created_at", "asc"),
+			"/users?select=*,posts(*,comments(*))&posts.comments.order=created_at.asc&posts.comments.limit=3",
+		},
+		{
+			"replaced",
+			NewQueryBuilder("users").Select("*", "posts(*)").OrderForeign("posts", "id", "asc").OrderForeign("posts", "id", "desc"),
+			"/users?select=*,posts(*)&posts.order=id.desc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if url := tt.builder.BuildURL(); url != tt.expected {
+				t.Errorf("BuildURL() = %v, want %v", url, tt.expected)
+			}
+			if errs := tt.builder.Validate(); len(errs) != 0 {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+
+	invalid := []*QueryBuilder{
+		NewQueryBuilder("users").OrderForeign("posts;drop", "id", "asc"),
+		NewQueryBuilder("users").OrderForeign("posts", "id", "sideways"),
+	}
+	for i, q := range invalid {
+		if errs := q.Validate(); len(errs) != 1 {
+			t.Errorf("invalid[%d].Validate() = %v, want one error", i, errs)
+		}
+	}
+}
+
+func TestEmbedDecodesNestedStructs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{
+			"id": 1,
+			"title": "Hello",
+			"author": {"name": "Ann", "avatar_url": "a.png"},
+			"comments": [{"body": "First", "author": {"name": "Bob"}}],
+			"editor": null
+		}]`))
+	}))
+	defer server.Close()
+
+	type Author struct {
+		Name      string
+		AvatarURL string
+	}
+	type Comment struct {
+		Body   string  `json:"body"`
+		Author *Author `json:"author"`
+	}
+	type Post struct {
+		ID       int       `json:"id"`
+		Title    string    `json:"title"`
+		Author   Author    `json:"author"`
+		Comments []Comment `json:"comments"`
+		Editor   *Author   `json:"editor"`
+	}
+
+	tests := []struct {
+		name    string
+		options []ClientOption
+		// wantAvatar is set when the untagged AvatarURL field matches avatar_url
+		wantAvatar bool
+	}{
+		{"default", nil, false},
+		{"column namer", []ClientOption{WithColumnNamer(NamerSnakeCase)}, true},
+		{"bytea hex", []ClientOption{WithByteaEncoding(ByteaHex)}, false},
+	}
+
+	for _, tt := range tests {
+		client := New(server.URL, "fake-api-key", tt.options...)
+
+		var posts []Post
+		err := client.Table("posts").
+			Select("id", "title").
+			Embed("author").Select("name", "avatar_url").End().
+			Embed("comments").Select("body", "author(name)").End().
+			Get(&posts)
+		if err != nil {
+			t.Fatalf("%s: Get() error = %v", tt.name, err)
+		}
+
+		if len(posts) != 1 {
+			t.Fatalf("%s: posts = %v, want one", tt.name, posts)
+		}
+		post := posts[0]
+		if post.Author.Name != "Ann" {
+			t.Errorf("%s: Author = %+v, want the embedded author", tt.name, post.Author)
+		}
+		if len(post.Comments) != 1 || post.Comments[0].Body != "First" || post.Comments[0].Author == nil || post.Comments[0].Author.Name != "Bob" {
+			t.Errorf("%s: Comments = %+v, want the embedded comments with their authors", tt.name, post.Comments)
+		}
+		if post.Editor != nil {
+			t.Errorf("%s: Editor = %+v, want nil for a null embed", tt.name, post.Editor)
+		}
+		if (post.Author.AvatarURL == "a.png") != tt.wantAvatar {
+			t.Errorf("%s: Author.AvatarURL = %q", tt.name, post.Author.AvatarURL)
+		}
+	}
+}
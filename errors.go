@@ -0,0 +1,104 @@
+package supabaseorm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrNotModified is returned when a conditional request gets 304 Not Modified
+var ErrNotModified = errors.New("not modified")
+
+// ErrReadOnlyView is returned when writing through a view builder without AllowWrites
+var ErrReadOnlyView = errors.New("view is read-only")
+
+// ErrLimitExceeded is returned when a read asks for more rows than WithStrictMaxLimit allows
+var ErrLimitExceeded = errors.New("limit exceeds maximum")
+
+// ErrTooManyAffected is returned when a write would affect more rows than MaxAffected allows
+var ErrTooManyAffected = errors.New("too many rows affected")
+
+// ErrNotAtomic is returned under RequireAtomic when an operation would take several
+// requests, each committed on its own. Move such work into an RPC function, which
+// PostgREST runs in a single transaction.
+var ErrNotAtomic = errors.New("operation is not atomic")
+
+// ErrUnsupportedFeature is returned when a query uses a feature that the PostgREST
+// version reported by ServerInfo, or assumed with WithAssumeServerVersion, doesn't have
+var ErrUnsupportedFeature = errors.New("feature not supported by server")
+
+// ErrCircuitOpen is returned without sending the request while the breaker set with
+// WithCircuitBreaker is open after repeated failures
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrCountUnavailable is returned by the count APIs when the response's Content-Range
+// header has no total, e.g. 0-9/* or a missing header, so the count is unknown
+var ErrCountUnavailable = errors.New("count not available")
+
+// ErrInvalidCursor is returned when a pagination cursor is malformed or its signature
+// doesn't match, see CursorCodec
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrPermissionDenied matches API errors caused by row level security or missing grants.
+// Note that RLS on reads filters rows instead of failing, so a denied SELECT looks like
+// an empty result; only writes and explicit denials can be detected.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrUnauthorized matches API errors with status 401, returned when the API key or
+// session token is missing, invalid or expired. Refreshing the session usually helps,
+// unlike ErrPermissionDenied.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// APIError is returned when the Supabase API responds with an error status
+type APIError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Details    string `json:"details"`
+	Hint       string `json:"hint"`
+	Body       string `json:"-"`
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %s", e.Body)
+}
+
+// Is reports whether the error matches target, so callers can use
+// errors.Is(err, ErrPermissionDenied) or errors.Is(err, ErrTooManyAffected)
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrPermissionDenied:
+		return e.IsPermissionDenied()
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrTooManyAffected:
+		// PGRST124: the max-affected preference was violated
+		return e.Code == "PGRST124"
+	}
+	return false
+}
+
+// IsPermissionDenied reports whether the request was rejected by row level security
+// (PostgreSQL error 42501, insufficient_privilege) or a 403 Forbidden status. A 401
+// means the caller wasn't authenticated and matches ErrUnauthorized instead.
+func (e *APIError) IsPermissionDenied() bool {
+	return e.Code == "42501" || e.StatusCode == http.StatusForbidden
+}
+
+// newAPIError builds an APIError from an error response,
+// decoding the PostgREST error fields when the body is JSON
+func newAPIError(resp *resty.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode(),
+		Body:       resp.String(),
+	}
+
+	// Details may be null or a string; ignore decoding failures and keep the raw body
+	_ = json.Unmarshal(resp.Body(), apiErr)
+
+	return apiErr
+}
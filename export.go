@@ -0,0 +1,79 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NDJSONPageSize is the number of rows GetNDJSON fetches per request
+const NDJSONPageSize = 1000
+
+// GetNDJSON writes the rows matching the query to w as newline-delimited JSON, one
+// object per line, e.g. for piping to jq or a bulk loader. Rows are fetched and written
+// NDJSONPageSize at a time, or the client's WithMaxLimit if lower, so large tables are
+// never held in memory; give the query an Order so pages don't repeat or skip rows.
+// Paging ends at the first empty page, since a server whose max-rows is below the page
+// size returns short pages before the end. A query with a Limit is fetched in one
+// request. Rows written before an error are left in w.
+func (q *QueryBuilder) GetNDJSON(ctx context.Context, w io.Writer) error {
+	if q.limitQuery != "" {
+		builder := q.clone()
+		builder.ctx = ctx
+		_, err := builder.writeNDJSON(w)
+		return err
+	}
+
+	offset := 0
+	if q.offsetQuery != "" {
+		offset, _ = strconv.Atoi(strings.TrimPrefix(q.offsetQuery, "offset="))
+	}
+
+	// Pages above WithMaxLimit would be clamped anyway
+	pageSize := NDJSONPageSize
+	if q.client != nil && q.client.maxLimit > 0 && q.client.maxLimit < pageSize {
+		pageSize = q.client.maxLimit
+	}
+
+	for {
+		builder := q.clone()
+		builder.ctx = ctx
+		builder.Limit(pageSize).Offset(offset)
+
+		n, err := builder.writeNDJSON(w)
+		if err != nil {
+			return fmt.Errorf("rows from %d: %w", offset, err)
+		}
+		if n == 0 {
+			return nil
+		}
+		offset += n
+	}
+}
+
+// writeNDJSON sends the query and writes the returned rows to w, one per line
+func (q *QueryBuilder) writeNDJSON(w io.Writer) (int, error) {
+	var rows []json.RawMessage
+	if err := q.execute(nil, &rows); err != nil {
+		return 0, err
+	}
+
+	var line bytes.Buffer
+	for _, row := range rows {
+		line.Reset()
+		// Rows are compacted so a pretty-printed response can't split them across lines
+		if err := json.Compact(&line, row); err != nil {
+			return 0, err
+		}
+		line.WriteByte('\n')
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(rows), nil
+}
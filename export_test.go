@@ -0,0 +1,106 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetNDJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Pretty-printed, as some proxies return it
+		w.Write([]byte("[\n  {\"id\": 1, \"name\": \"Ann\"},\n  {\"id\": 2, \"name\": \"Bob\"},\n  {\"id\": 3, \"name\": null}\n]"))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var out bytes.Buffer
+	if err := client.Table("users").Order("id", "asc").Limit(3).GetNDJSON(context.Background(), &out); err != nil {
+		t.Fatalf("GetNDJSON() error = %v", err)
+	}
+
+	want := `{"id":1,"name":"Ann"}` + "\n" + `{"id":2,"name":"Bob"}` + "\n" + `{"id":3,"name":null}` + "\n"
+	if out.String() != want {
+		t.Errorf("GetNDJSON() wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestGetNDJSONPages(t *testing.T) {
+	const total = NDJSONPageSize + 500
+
+	tests := []struct {
+		name         string
+		maxRows      int
+		wantRequests []string
+	}{
+		{
+			name:    "full pages",
+			maxRows: total,
+			wantRequests: []string{
+				fmt.Sprintf("%d@0", NDJSONPageSize),
+				fmt.Sprintf("%d@%d", NDJSONPageSize, NDJSONPageSize),
+				fmt.Sprintf("%d@%d", NDJSONPageSize, total),
+			},
+		},
+		{
+			// Every page is short when the server's max-rows is below the page size
+			name:    "server max-rows",
+			maxRows: 400,
+			wantRequests: []string{
+				fmt.Sprintf("%d@0", NDJSONPageSize),
+				fmt.Sprintf("%d@400", NDJSONPageSize),
+				fmt.Sprintf("%d@800", NDJSONPageSize),
+				fmt.Sprintf("%d@1200", NDJSONPageSize),
+				fmt.Sprintf("%d@%d", NDJSONPageSize, total),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				requests = append(requests, query.Get("limit")+"@"+query.Get("offset"))
+
+				limit, _ := strconv.Atoi(query.Get("limit"))
+				offset, _ := strconv.Atoi(query.Get("offset"))
+				limit = min(limit, tt.maxRows)
+				rows := []map[string]int{}
+				for id := offset + 1; id <= total && id <= offset+limit; id++ {
+					rows = append(rows, map[string]int{"id": id})
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(rows)
+			}))
+			defer server.Close()
+
+			client := New(server.URL, "fake-api-key")
+
+			var out bytes.Buffer
+			if err := client.Table("events").Order("id", "asc").GetNDJSON(context.Background(), &out); err != nil {
+				t.Fatalf("GetNDJSON() error = %v", err)
+			}
+
+			if fmt.Sprint(requests) != fmt.Sprint(tt.wantRequests) {
+				t.Errorf("requests = %v, want %v", requests, tt.wantRequests)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(lines) != total {
+				t.Fatalf("GetNDJSON() wrote %d lines, want %d", len(lines), total)
+			}
+			if lines[0] != `{"id":1}` || lines[total-1] != fmt.Sprintf(`{"id":%d}`, total) {
+				t.Errorf("first and last lines = %s, %s", lines[0], lines[total-1])
+			}
+		})
+	}
+}
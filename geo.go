@@ -0,0 +1,121 @@
+package supabaseorm
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// NearbyFunctionSuffix names the server function used by the geo helpers: queries on
+// stores read from stores_nearby. PostgREST can't call PostGIS functions in filters,
+// so the distance check runs in a function returning the table's rows, e.g.
+//
+//	create function stores_nearby(geo_column text, lat float8, lng float8, meters float8 default null)
+//	returns setof stores language plpgsql stable as $$
+//	begin
+//	  return query execute format(
+//	    'select * from stores
+//	     where $3 is null or st_dwithin(%1$I::geography, st_makepoint($2, $1)::geography, $3)
+//	     order by %1$I::geography <-> st_makepoint($2, $1)::geography',
+//	    geo_column) using lat, lng, meters;
+//	end $$;
+//
+// The query's filters, order and limit apply to the function's rows as they would to
+// the table's.
+const NearbyFunctionSuffix = "_nearby"
+
+// WhereWithinDistance keeps the rows whose geoColumn, a PostGIS geometry or geography,
+// is within meters of the point at lat, lng. It reads through the <table>_nearby server
+// function, see NearbyFunctionSuffix, and can only be used in reads.
+func (q *QueryBuilder) WhereWithinDistance(geoColumn string, lat, lng, meters float64) *QueryBuilder {
+	if !q.nearby(geoColumn, lat, lng) {
+		return q
+	}
+	if meters < 0 || math.IsNaN(meters) {
+		q.errs = append(q.errs, fmt.Errorf("invalid distance %v for %s", meters, geoColumn))
+		return q
+	}
+
+	q.setFunctionArg("meters", strconv.FormatFloat(meters, 'f', -1, 64))
+	return q
+}
+
+// OrderByDistance sorts the rows nearest-first from the point at lat, lng on geoColumn.
+// It reads through the <table>_nearby server function, which returns its rows ordered
+// by distance (see NearbyFunctionSuffix), so it replaces any previous Order and the
+// client's default order; a later Order replaces it in turn. When the server has no
+// such function, the query fails with an error naming the function to create.
+func (q *QueryBuilder) OrderByDistance(geoColumn string, lat, lng float64) *QueryBuilder {
+	if !q.nearby(geoColumn, lat, lng) {
+		return q
+	}
+
+	q.orderQuery = ""
+	q.distanceOrder = true
+	return q
+}
+
+// functionError explains an error caused by a missing server function
+func (q *QueryBuilder) functionError(err *APIError) error {
+	if q.function == "" || err.Code != "PGRST202" {
+		return err
+	}
+	return fmt.Errorf("%s requires the server function %s, see NearbyFunctionSuffix: %w", q.table, q.function, err)
+}
+
+// nearby routes the query through the table's nearby function for a point on geoColumn
+func (q *QueryBuilder) nearby(geoColumn string, lat, lng float64) bool {
+	if !identifierPattern.MatchString(geoColumn) {
+		q.errs = append(q.errs, fmt.Errorf("invalid geo column %q", geoColumn))
+		return false
+	}
+	if math.Abs(lat) > 90 || math.Abs(lng) > 180 || math.IsNaN(lat) || math.IsNaN(lng) {
+		q.errs = append(q.errs, fmt.Errorf("invalid coordinates %v, %v", lat, lng))
+		return false
+	}
+
+	if q.function != "" && q.function != q.table+NearbyFunctionSuffix {
+		q.errs = append(q.errs, fmt.Errorf("query on %s already reads from %s", q.table, q.function))
+		return false
+	}
+	if column := q.functionArg("geo_column"); column != "" && column != geoColumn {
+		q.errs = append(q.errs, fmt.Errorf("distance on %s and %s in one query", column, geoColumn))
+		return false
+	}
+
+	q.function = q.table + NearbyFunctionSuffix
+	q.setFunctionArg("geo_column", geoColumn)
+	q.setFunctionArg("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	q.setFunctionArg("lng", strconv.FormatFloat(lng, 'f', -1, 64))
+	return true
+}
+
+// functionArg returns the argument sent to the query's function, or ""
+func (q *QueryBuilder) functionArg(name string) string {
+	for _, arg := range q.functionArgs {
+		if arg.key == name {
+			return arg.value
+		}
+	}
+	return ""
+}
+
+// setFunctionArg sets an argument of the query's function
+func (q *QueryBuilder) setFunctionArg(name, value string) {
+	for i, arg := range q.functionArgs {
+		if arg.key == name {
+			q.functionArgs[i].value = value
+			return
+		}
+	}
+	q.functionArgs = append(q.functionArgs, rawParam{key: name, value: value})
+}
+
+// validateFunction checks that a query reading through a function is a read
+func (q *QueryBuilder) validateFunction() error {
+	if q.function == "" || (q.method != http.MethodPost && q.method != http.MethodPatch && q.method != http.MethodDelete) {
+		return nil
+	}
+	return fmt.Errorf("%s on %s: queries through %s can only read", q.method, q.table, q.function)
+}
@@ -0,0 +1,131 @@
+package supabaseorm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWhereWithinDistance(t *testing.T) {
+	var path string
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":1,"name":"Downtown"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var stores []map[string]interface{}
+	err := client.Table("stores").
+		Select("id", "name").
+		Where("open", "is", true).
+		WhereWithinDistance("location", 40.4168, -3.7038, 1500).
+		Get(&stores)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if path != "/rest/v1/rpc/stores_nearby" {
+		t.Errorf("path = %q, want /rest/v1/rpc/stores_nearby", path)
+	}
+	want := map[string]string{
+		"geo_column": "location",
+		"lat":        "40.4168",
+		"lng":        "-3.7038",
+		"meters":     "1500",
+		"open":       "is.true",
+		"select":     "id,name",
+	}
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, query.Get(key), value)
+		}
+	}
+	if len(stores) != 1 {
+		t.Errorf("Get() = %v, want one store", stores)
+	}
+
+	invalid := []*QueryBuilder{
+		NewQueryBuilder("stores").WhereWithinDistance("location", 95, 0, 100),
+		NewQueryBuilder("stores").WhereWithinDistance("location", 0, 0, -1),
+		NewQueryBuilder("stores").WhereWithinDistance("location; drop", 0, 0, 100),
+	}
+	for i, q := range invalid {
+		if errs := q.Validate(); len(errs) != 1 {
+			t.Errorf("query %d: Validate() = %v, want one error", i, errs)
+		}
+	}
+
+	path = ""
+	if err := client.Table("stores").Where("id", "eq", 1).WhereWithinDistance("location", 0, 0, 100).Delete(); err == nil {
+		t.Error("Delete() through the nearby function error = nil, want error")
+	}
+	if path != "" {
+		t.Errorf("Delete() reached the server at %s", path)
+	}
+}
+
+func TestOrderByDistance(t *testing.T) {
+	var path string
+	var query url.Values
+	missing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		if missing {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"PGRST202","message":"Could not find the function public.stores_nearby"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key").WithDefaultOrder("id", "asc")
+
+	var stores []map[string]interface{}
+	err := client.Table("stores").
+		Order("name", "asc").
+		OrderByDistance("location", 51.5074, -0.1278).
+		Limit(10).
+		Get(&stores)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if path != "/rest/v1/rpc/stores_nearby" {
+		t.Errorf("path = %q, want /rest/v1/rpc/stores_nearby", path)
+	}
+	if query.Get("geo_column") != "location" || query.Get("lat") != "51.5074" || query.Get("lng") != "-0.1278" {
+		t.Errorf("function arguments = %v", query)
+	}
+	if query.Has("meters") {
+		t.Errorf("meters = %q, want no distance limit", query.Get("meters"))
+	}
+	if query.Has("order") {
+		t.Errorf("order = %q, want the function's nearest-first order", query.Get("order"))
+	}
+
+	combined := NewQueryBuilder("stores").WhereWithinDistance("location", 1, 2, 500).OrderByDistance("location", 1, 2)
+	if url := combined.BuildURL(); url != "/rpc/stores_nearby?geo_column=location&lat=1&lng=2&meters=500" {
+		t.Errorf("BuildURL() = %q", url)
+	}
+	if errs := NewQueryBuilder("stores").WhereWithinDistance("location", 1, 2, 500).OrderByDistance("area", 1, 2).Validate(); len(errs) != 1 {
+		t.Errorf("Validate() with two geo columns = %v, want one error", errs)
+	}
+
+	missing = true
+	err = client.Table("stores").OrderByDistance("location", 51.5074, -0.1278).Get(&stores)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "requires the server function stores_nearby") {
+		t.Errorf("Get() without the function error = %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package supabaseorm
+
+import (
+	"context"
+	"reflect"
+)
+
+// Model hooks are registered by implementing these interfaces on a row type, with a
+// pointer or value receiver. They run on every element when the data or result is a
+// slice, and an error returned by a hook aborts the operation.
+
+// AfterFinder is implemented by row types that post-process rows after a read,
+// e.g. to decrypt fields or compute derived values
+type AfterFinder interface {
+	AfterFind(ctx context.Context) error
+}
+
+// BeforeInserter is implemented by row types that prepare a row before it is inserted
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// BeforeUpdater is implemented by row types that prepare a row before it is used in an update
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// hookContext returns the query's context for hooks
+func (q *QueryBuilder) hookContext() context.Context {
+	if q.ctx != nil {
+		return q.ctx
+	}
+	return context.Background()
+}
+
+// beforeInsert runs BeforeInsert hooks on data. Values that aren't pointers are copied
+// so hooks with pointer receivers can modify them, and the copy is returned to be sent.
+func (q *QueryBuilder) beforeInsert(data interface{}) (interface{}, error) {
+	ctx := q.hookContext()
+	return runHooks(data, func(row interface{}) error {
+		if hook, ok := row.(BeforeInserter); ok {
+			return hook.BeforeInsert(ctx)
+		}
+		return nil
+	})
+}
+
+// beforeUpdate runs BeforeUpdate hooks on data, see beforeInsert
+func (q *QueryBuilder) beforeUpdate(data interface{}) (interface{}, error) {
+	ctx := q.hookContext()
+	return runHooks(data, func(row interface{}) error {
+		if hook, ok := row.(BeforeUpdater); ok {
+			return hook.BeforeUpdate(ctx)
+		}
+		return nil
+	})
+}
+
+// afterFind runs AfterFind hooks on the rows decoded into result
+func (q *QueryBuilder) afterFind(result interface{}) error {
+	if !hasHookMethods(reflect.TypeOf(result)) {
+		return nil
+	}
+
+	ctx := q.hookContext()
+	_, err := runHooks(result, func(row interface{}) error {
+		if hook, ok := row.(AfterFinder); ok {
+			return hook.AfterFind(ctx)
+		}
+		return nil
+	})
+	return err
+}
+
+// runHooks calls hook with a pointer to data, or to each element when data is a slice
+// or array. It returns data, or an addressable copy of it when data isn't a pointer.
+func runHooks(data interface{}, hook func(row interface{}) error) (interface{}, error) {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() {
+		return data, nil
+	}
+
+	if v.Kind() != reflect.Ptr {
+		// Skip the copy for types without hooks, such as maps built by StructToMap
+		if !hasHookMethods(v.Type()) {
+			return data, nil
+		}
+		copied := reflect.New(v.Type())
+		copied.Elem().Set(v)
+		v = copied
+		data = copied.Interface()
+	}
+
+	return data, walkHooks(v, hook)
+}
+
+// walkHooks calls hook on every addressable row reachable from v
+func walkHooks(v reflect.Value, hook func(row interface{}) error) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+			return hook(v.Interface())
+		}
+		return walkHooks(v.Elem(), hook)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkHooks(v.Index(i), hook); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if v.CanAddr() {
+			return hook(v.Addr().Interface())
+		}
+		return hook(v.Interface())
+	}
+
+	return nil
+}
+
+// hasHookMethods reports whether rows of type t, or the elements of a slice of them, have hooks
+func hasHookMethods(t reflect.Type) bool {
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(reflect.TypeOf((*AfterFinder)(nil)).Elem()) ||
+		ptr.Implements(reflect.TypeOf((*BeforeInserter)(nil)).Elem()) ||
+		ptr.Implements(reflect.TypeOf((*BeforeUpdater)(nil)).Elem())
+}
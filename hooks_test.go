@@ -0,0 +1,95 @@
+package supabaseorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type hookedUser struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	FullName  string `json:"-"`
+}
+
+func (u *hookedUser) AfterFind(ctx context.Context) error {
+	u.FullName = u.FirstName + " " + u.LastName
+	return nil
+}
+
+func (u *hookedUser) BeforeInsert(ctx context.Context) error {
+	if u.Email == "" {
+		return errors.New("email is required")
+	}
+	u.Email = strings.ToLower(u.Email)
+	return nil
+}
+
+func (u *hookedUser) BeforeUpdate(ctx context.Context) error {
+	u.LastName = strings.TrimSpace(u.LastName)
+	return nil
+}
+
+func TestModelHooks(t *testing.T) {
+	var body map[string]interface{}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[{"id":1,"first_name":"John","last_name":"Doe"},{"id":2,"first_name":"Jane","last_name":"Roe"}]`))
+		default:
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var users []hookedUser
+	if err := client.Table("users").Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(users) != 2 || users[0].FullName != "John Doe" || users[1].FullName != "Jane Roe" {
+		t.Errorf("Get() = %+v, want FullName computed by AfterFind", users)
+	}
+
+	var pointers []*hookedUser
+	if err := client.Table("users").Get(&pointers); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if pointers[1].FullName != "Jane Roe" {
+		t.Errorf("Get() = %+v, want FullName computed by AfterFind", pointers[1])
+	}
+
+	// Values are copied so pointer-receiver hooks still apply to the request body
+	if err := client.Table("users").Insert(hookedUser{Email: "John@Example.COM"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if body["email"] != "john@example.com" {
+		t.Errorf("inserted email = %v, want lowercased by BeforeInsert", body["email"])
+	}
+
+	if err := client.Table("users").Where("id", "eq", 1).Update(&hookedUser{ID: 1, LastName: " Doe "}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if body["last_name"] != "Doe" {
+		t.Errorf("updated last_name = %v, want trimmed by BeforeUpdate", body["last_name"])
+	}
+
+	before := requests
+	if err := client.Table("users").Insert(&hookedUser{}); err == nil || err.Error() != "email is required" {
+		t.Errorf("Insert() error = %v, want the BeforeInsert error", err)
+	}
+	if requests != before {
+		t.Error("Insert() sent a request after BeforeInsert failed")
+	}
+}
@@ -0,0 +1,353 @@
+package supabaseorm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultJWTAudience is the audience of tokens issued to signed-in users
+const DefaultJWTAudience = "authenticated"
+
+// jwksCacheTTL is how long fetched signing keys are reused
+const jwksCacheTTL = 10 * time.Minute
+
+// jwksRefreshInterval is the least time between two JWKS fetches, so tokens naming
+// unknown keys can't make every verification call the auth server
+const jwksRefreshInterval = time.Minute
+
+// ErrInvalidToken is returned when a JWT fails verification
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims holds the claims of a Supabase-issued access token
+type Claims struct {
+	Subject      string                 `json:"sub"`
+	Role         string                 `json:"role"`
+	Email        string                 `json:"email"`
+	Phone        string                 `json:"phone"`
+	SessionID    string                 `json:"session_id"`
+	Issuer       string                 `json:"iss"`
+	Audience     audience               `json:"aud"`
+	ExpiresAt    int64                  `json:"exp"`
+	NotBefore    int64                  `json:"nbf"`
+	IssuedAt     int64                  `json:"iat"`
+	AppMetadata  map[string]interface{} `json:"app_metadata"`
+	UserMetadata map[string]interface{} `json:"user_metadata"`
+}
+
+// audience decodes the aud claim, which may be a string or a list
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// contains reports whether the audience includes aud
+func (a audience) contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// jwtHeader is the decoded JOSE header of a token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jsonWebKey is a public key from the project's JWKS
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// jwksCache caches the project's signing keys by key id
+type jwksCache struct {
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// attemptedAt is when the last fetch started, successful or not
+	attemptedAt time.Time
+	// refreshing is closed when the fetch in flight finishes, nil when there is none
+	refreshing chan struct{}
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// clock returns the current time
+func (c *jwksCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// WithJWTSecret sets the project's JWT secret used to verify HS256 tokens locally
+func WithJWTSecret(secret string) ClientOption {
+	return func(c *Client) {
+		c.jwtSecret = secret
+	}
+}
+
+// WithJWTAudience sets the audience VerifyJWT expects, DefaultJWTAudience by default
+func WithJWTAudience(aud string) ClientOption {
+	return func(c *Client) {
+		c.jwtAudience = aud
+	}
+}
+
+// VerifyJWT validates a Supabase-issued access token without calling the auth server
+// for every request. HS256 tokens are checked against the secret set with WithJWTSecret;
+// asymmetric tokens (RS256, ES256) against the project's JWKS, which is fetched once and
+// cached. The signature, expiry, not-before time and audience are verified.
+func (a *Auth) VerifyJWT(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := a.verifySignature(ctx, header, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	now := time.Now().Unix()
+	if claims.ExpiresAt == 0 || now >= claims.ExpiresAt {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if now < claims.NotBefore {
+		return nil, fmt.Errorf("%w: token not valid yet", ErrInvalidToken)
+	}
+
+	expected := a.client.jwtAudience
+	if expected == "" {
+		expected = DefaultJWTAudience
+	}
+	if !claims.Audience.contains(expected) {
+		return nil, fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, []string(claims.Audience))
+	}
+
+	return &claims, nil
+}
+
+// verifySignature checks the token signature for the algorithm in the header
+func (a *Auth) verifySignature(ctx context.Context, header jwtHeader, signed, signature []byte) error {
+	// Reject other algorithms, such as none, before looking up a key
+	switch header.Algorithm {
+	case "HS256", "RS256", "ES256":
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+
+	if header.Algorithm == "HS256" {
+		if a.client.jwtSecret == "" {
+			return fmt.Errorf("%w: HS256 token but no JWT secret configured", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, []byte(a.client.jwtSecret))
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		return nil
+	}
+
+	key, err := a.signingKey(ctx, header.KeyID)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(signed)
+
+	switch header.Algorithm {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+	}
+
+	return nil
+}
+
+// signingKey returns the cached JWKS key with the given id, refreshing the cache when
+// it is stale or the key is unknown. Refreshes happen at most once per
+// jwksRefreshInterval and outside the lock, with concurrent callers waiting for the
+// fetch in flight; until the next one a stale key is still used.
+func (a *Auth) signingKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	cache := &a.jwks
+	for {
+		cache.mu.Lock()
+		key, known := cache.keys[keyID]
+		now := cache.clock()
+		if known && now.Sub(cache.fetchedAt) < jwksCacheTTL {
+			cache.mu.Unlock()
+			return key, nil
+		}
+
+		if done := cache.refreshing; done != nil {
+			cache.mu.Unlock()
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if !cache.attemptedAt.IsZero() && now.Sub(cache.attemptedAt) < jwksRefreshInterval {
+			cache.mu.Unlock()
+			if known {
+				return key, nil
+			}
+			return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, keyID)
+		}
+
+		done := make(chan struct{})
+		previous := cache.attemptedAt
+		cache.refreshing = done
+		cache.attemptedAt = now
+		cache.mu.Unlock()
+
+		keys, err := a.fetchJWKS(ctx)
+
+		cache.mu.Lock()
+		if err == nil {
+			cache.keys = keys
+			cache.fetchedAt = cache.clock()
+		} else if ctx.Err() != nil {
+			// A fetch abandoned by its caller doesn't hold back the next one
+			cache.attemptedAt = previous
+		}
+		cache.refreshing = nil
+		close(done)
+		cache.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fetchJWKS fetches the project's signing keys, skipping keys of unsupported types
+func (a *Auth) fetchJWKS(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.client.Do(ctx, "GET", "/auth/v1/.well-known/jwks.json", nil, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+// publicKey converts a JWK into an RSA or ECDSA public key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,189 @@
+package supabaseorm
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signToken builds a JWT with the given header and claims, signed by sign
+func signToken(t *testing.T, header, claims map[string]interface{}, sign func([]byte) []byte) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(header) + "." + encode(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func TestVerifyJWTWithSecret(t *testing.T) {
+	const secret = "super-secret-jwt-token"
+	hs256 := func(data []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(data)
+		return mac.Sum(nil)
+	}
+
+	header := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	claims := map[string]interface{}{
+		"sub":   "user-1",
+		"role":  "authenticated",
+		"email": "test@example.com",
+		"aud":   "authenticated",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+
+	auth := New("https://example.supabase.co", "anon-key", WithJWTSecret(secret)).Auth()
+
+	got, err := auth.VerifyJWT(context.Background(), signToken(t, header, claims, hs256))
+	if err != nil {
+		t.Fatalf("VerifyJWT() error = %v", err)
+	}
+	if got.Subject != "user-1" || got.Role != "authenticated" || got.Email != "test@example.com" {
+		t.Errorf("VerifyJWT() = %+v", got)
+	}
+
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	if _, err := auth.VerifyJWT(context.Background(), signToken(t, header, claims, hs256)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyJWT() with expired token error = %v, want ErrInvalidToken", err)
+	}
+
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	forged := signToken(t, header, claims, func(data []byte) []byte { return []byte("forged") })
+	if _, err := auth.VerifyJWT(context.Background(), forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyJWT() with bad signature error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyJWTWithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/v1/.well-known/jwks.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fetches++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	rs256 := func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+
+	header := map[string]interface{}{"alg": "RS256", "kid": "key-1"}
+	claims := map[string]interface{}{
+		"sub": "user-2",
+		"aud": []string{"authenticated"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	token := signToken(t, header, claims, rs256)
+
+	auth := New(server.URL, "anon-key").Auth()
+	for i := 0; i < 2; i++ {
+		got, err := auth.VerifyJWT(context.Background(), token)
+		if err != nil {
+			t.Fatalf("VerifyJWT() error = %v", err)
+		}
+		if got.Subject != "user-2" {
+			t.Errorf("VerifyJWT() subject = %q, want user-2", got.Subject)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", fetches)
+	}
+
+	claims["aud"] = "other"
+	if _, err := auth.VerifyJWT(context.Background(), signToken(t, header, claims, rs256)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyJWT() with wrong audience error = %v, want ErrInvalidToken", err)
+	}
+
+	claims["aud"] = "authenticated"
+	claims["nbf"] = time.Now().Add(time.Hour).Unix()
+	if _, err := auth.VerifyJWT(context.Background(), signToken(t, header, claims, rs256)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyJWT() before nbf error = %v, want ErrInvalidToken", err)
+	}
+	delete(claims, "nbf")
+
+	// Unsupported algorithms are rejected without fetching keys
+	none := signToken(t, map[string]interface{}{"alg": "none", "kid": "forged"}, claims, func([]byte) []byte { return nil })
+	if _, err := auth.VerifyJWT(context.Background(), none); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyJWT() with alg none error = %v, want ErrInvalidToken", err)
+	}
+	if fetches != 1 {
+		t.Errorf("JWKS fetched %d times after alg none, want 1", fetches)
+	}
+}
+
+func TestVerifyJWTRefreshLimit(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	auth := New(server.URL, "anon-key").Auth()
+	auth.jwks.now = func() time.Time { return now }
+
+	claims := map[string]interface{}{"aud": "authenticated", "exp": time.Now().Add(time.Hour).Unix()}
+	forged := func(kid string) string {
+		header := map[string]interface{}{"alg": "RS256", "kid": kid}
+		return signToken(t, header, claims, func([]byte) []byte { return []byte("forged") })
+	}
+
+	// Tokens naming unknown keys trigger one fetch per interval
+	for i := 0; i < 5; i++ {
+		if _, err := auth.VerifyJWT(context.Background(), forged(fmt.Sprintf("kid-%d", i))); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("VerifyJWT() with unknown key error = %v, want ErrInvalidToken", err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", fetches)
+	}
+
+	now = now.Add(jwksRefreshInterval)
+	auth.VerifyJWT(context.Background(), forged("kid-rotated"))
+	if fetches != 2 {
+		t.Errorf("JWKS fetched %d times after the interval, want 2", fetches)
+	}
+}
@@ -0,0 +1,127 @@
+package supabaseorm
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ColumnNamer maps a Go struct field name to a column name
+type ColumnNamer func(fieldName string) string
+
+// WithColumnNamer renames struct fields without a json tag when rows are written and
+// read, e.g. WithColumnNamer(NamerSnakeCase) sends a CreatedAt field as created_at and
+// decodes created_at back into it. Fields with a json tag keep the tagged name.
+func WithColumnNamer(namer ColumnNamer) ClientOption {
+	return func(c *Client) {
+		c.columnNamer = namer
+	}
+}
+
+// NamerSnakeCase converts a field name to snake_case, keeping initialisms together,
+// e.g. UserID becomes user_id and HTTPStatus becomes http_status
+func NamerSnakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a word after a lowercase letter or digit, or at the last
+			// capital of an initialism followed by a lowercase letter
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// renameColumns walks decoded json alongside the Go type it maps to, renaming the keys
+// of struct fields without a json name: to columns when writing, and from columns back
+// to field names when reading. The value is used as in rewriteBytea.
+func renameColumns(data interface{}, t reflect.Type, v reflect.Value, namer ColumnNamer, toColumns bool) interface{} {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		switch {
+		case v.IsValid():
+			if v.IsNil() {
+				return data
+			}
+			v = v.Elem()
+			t = v.Type()
+		case t.Kind() == reflect.Ptr:
+			t = t.Elem()
+		default:
+			return data
+		}
+	}
+
+	// Types with their own json encoding, e.g. time.Time, are left alone
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return data
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		items, ok := data.([]interface{})
+		if !ok {
+			return data
+		}
+		for i := range items {
+			var item reflect.Value
+			if v.IsValid() && i < v.Len() {
+				item = v.Index(i)
+			}
+			items[i] = renameColumns(items[i], t.Elem(), item, namer, toColumns)
+		}
+	case reflect.Map:
+		// Map keys are column names already; only their values are walked
+		obj, ok := data.(map[string]interface{})
+		if !ok || t.Key().Kind() != reflect.String {
+			return data
+		}
+		for key, item := range obj {
+			var value reflect.Value
+			if v.IsValid() {
+				value = v.MapIndex(reflect.ValueOf(key).Convert(t.Key()))
+			}
+			obj[key] = renameColumns(item, t.Elem(), value, namer, toColumns)
+		}
+	case reflect.Struct:
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return data
+		}
+		for _, field := range reflect.VisibleFields(t) {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" || (field.Anonymous && name == "") {
+				continue
+			}
+
+			from, to := name, name
+			if name == "" {
+				from, to = field.Name, namer(field.Name)
+				if !toColumns {
+					from, to = to, from
+				}
+			}
+
+			item, ok := obj[from]
+			if !ok {
+				continue
+			}
+
+			var value reflect.Value
+			if v.IsValid() {
+				value, _ = v.FieldByIndexErr(field.Index)
+			}
+			delete(obj, from)
+			obj[to] = renameColumns(item, field.Type, value, namer, toColumns)
+		}
+	}
+
+	return data
+}
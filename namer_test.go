@@ -0,0 +1,76 @@
+package supabaseorm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNamerSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Name":       "name",
+		"CreatedAt":  "created_at",
+		"UserID":     "user_id",
+		"HTTPStatus": "http_status",
+		"Address2":   "address2",
+		"ID":         "id",
+	}
+
+	for name, want := range tests {
+		if got := NamerSnakeCase(name); got != want {
+			t.Errorf("NamerSnakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWithColumnNamer(t *testing.T) {
+	type Profile struct {
+		DisplayName string
+	}
+	type Account struct {
+		ID        int `json:"id"`
+		FirstName string
+		CreatedAt string
+		Profile   Profile
+	}
+
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`[{"id":7,"first_name":"John","created_at":"2024-01-01","profile":{"display_name":"johnny"}}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key", WithColumnNamer(NamerSnakeCase))
+
+	account := &Account{FirstName: "John", Profile: Profile{DisplayName: "johnny"}}
+	if err := client.Table("accounts").Upsert(account); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	for _, key := range []string{"id", "first_name", "created_at", "profile"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("body %v has no %s key", body, key)
+		}
+	}
+	if _, ok := body["FirstName"]; ok {
+		t.Errorf("body %v still has the field name FirstName", body)
+	}
+	if profile, _ := body["profile"].(map[string]interface{}); profile["display_name"] != "johnny" {
+		t.Errorf("nested body = %v, want display_name", body["profile"])
+	}
+
+	if account.ID != 7 || account.CreatedAt != "2024-01-01" || account.Profile.DisplayName != "johnny" {
+		t.Errorf("decoded account = %+v", account)
+	}
+
+	if err := client.Table("accounts").Where("id", "eq", 7).Update(Account{ID: 7, FirstName: "Jim"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if body["first_name"] != "Jim" {
+		t.Errorf("update body = %v, want first_name", body)
+	}
+}
@@ -0,0 +1,37 @@
+package supabaseorm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ParallelLimit is the number of query functions Parallel runs at once
+const ParallelLimit = 8
+
+// Parallel runs independent queries concurrently, e.g. the counts and rows of a dashboard,
+// at most ParallelLimit at a time, and waits for all of them. Each function receives this
+// client, which is safe for concurrent use; results are passed out through variables
+// captured by the functions, each written by one function only. The errors of failed
+// functions are joined in argument order, each prefixed with its index, so errors.Is
+// and errors.As see every one of them.
+func (c *Client) Parallel(fns ...func(c *Client) error) error {
+	errs := make([]error, len(fns))
+	sem := make(chan struct{}, ParallelLimit)
+
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, fn func(c *Client) error) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(c); err != nil {
+				errs[i] = fmt.Errorf("query %d: %w", i, err)
+			}
+		}(i, fn)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
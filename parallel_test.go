@@ -0,0 +1,90 @@
+package supabaseorm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"42P01","message":"relation does not exist"}`))
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Range", "*/42")
+		default:
+			w.Write([]byte(`[{"id":1},{"id":2}]`))
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var users, orders []map[string]interface{}
+	var signups int64
+	err := client.Parallel(
+		func(c *Client) error { return c.Table("users").Get(&users) },
+		func(c *Client) error { return c.Table("orders").Limit(10).Get(&orders) },
+		func(c *Client) (err error) {
+			signups, err = c.Table("signups").CountContext(context.Background())
+			return err
+		},
+	)
+	if err != nil {
+		t.Fatalf("Parallel() error = %v", err)
+	}
+
+	if len(users) != 2 || len(orders) != 2 || signups != 42 {
+		t.Errorf("results = %d users, %d orders, %d signups", len(users), len(orders), signups)
+	}
+	if maxInFlight != 3 {
+		t.Errorf("queries in flight = %d, want all 3 at once", maxInFlight)
+	}
+
+	// Errors of every failed query are joined
+	var missing []map[string]interface{}
+	err = client.Parallel(
+		func(c *Client) error { return c.Table("users").Get(&users) },
+		func(c *Client) error { return c.Table("missing").Get(&missing) },
+		func(c *Client) error { return errors.New("bad input") },
+	)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "query 1:") || !strings.Contains(err.Error(), "query 2: bad input") {
+		t.Errorf("Parallel() error = %v, want the failures of queries 1 and 2", err)
+	}
+
+	// At most ParallelLimit run at once
+	atomic.StoreInt32(&maxInFlight, 0)
+	fns := make([]func(c *Client) error, ParallelLimit+4)
+	for i := range fns {
+		fns[i] = func(c *Client) error {
+			var rows []map[string]interface{}
+			return c.Table("users").Get(&rows)
+		}
+	}
+	if err := client.Parallel(fns...); err != nil {
+		t.Fatalf("Parallel() error = %v", err)
+	}
+	if maxInFlight > ParallelLimit {
+		t.Errorf("queries in flight = %d, want at most %d", maxInFlight, ParallelLimit)
+	}
+}
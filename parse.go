@@ -0,0 +1,59 @@
+package supabaseorm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParseQuery builds a query on table from a PostgREST query string, e.g.
+// "select=id,name&age=gte.18&order=created_at.desc&limit=10", so a gateway can
+// inspect and re-authorize a client's query before forwarding it. Filters keep their
+// order, and the query is rejected when Validate reports any problem, such as an
+// unknown operator.
+func ParseQuery(table, rawQuery string) (*QueryBuilder, error) {
+	q := NewQueryBuilder(table)
+
+	for _, pair := range strings.Split(strings.TrimPrefix(rawQuery, "?"), "&") {
+		if pair == "" {
+			continue
+		}
+
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter %q: %w", rawKey, err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+
+		switch key {
+		case "select":
+			q.Select(value)
+		case "order":
+			q.orderQuery = "order=" + value
+		case "limit", "offset":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			if key == "limit" {
+				q.Limit(n)
+			} else {
+				q.Offset(n)
+			}
+		default:
+			q.filters = append(q.filters, key+"="+value)
+		}
+	}
+
+	if errs := q.Validate(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return q, nil
+}
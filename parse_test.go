@@ -0,0 +1,45 @@
+package supabaseorm
+
+import (
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	raw := "select=id,name,posts(title)&age=gte.18&status=in.(active,pending)&or=(role.eq.admin,and(verified.is.true,score.gt.90))&order=created_at.desc,id.asc&limit=10&offset=20"
+
+	qb, err := ParseQuery("users", raw)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+
+	if got, want := qb.BuildURL(), "/users?"+raw; got != want {
+		t.Errorf("BuildURL() = %v, want %v", got, want)
+	}
+
+	// Escaped input decodes to the same query
+	escaped, err := ParseQuery("users", "select=id%2Cname&name=eq.John%20Doe")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	if got, want := escaped.BuildURL(), "/users?select=id,name&name=eq.John Doe"; got != want {
+		t.Errorf("BuildURL() = %v, want %v", got, want)
+	}
+
+	invalid := []string{
+		"age=between.1.5",
+		"or=(age.between.1.5)",
+		"or=(role.eq.admin,and(score.near.90))",
+		"order=created_at.sideways",
+		"limit=-1",
+		"offset=ten",
+	}
+	for _, query := range invalid {
+		if _, err := ParseQuery("users", query); err == nil {
+			t.Errorf("ParseQuery(%q) error = nil, want error", query)
+		}
+	}
+
+	if _, err := ParseQuery("users; drop table users", "select=*"); err == nil {
+		t.Error("ParseQuery() with invalid table error = nil, want error")
+	}
+}
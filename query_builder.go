@@ -1,11 +1,17 @@
 package supabaseorm
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -22,13 +28,54 @@ type QueryBuilder struct {
 	limitQuery   string
 	offsetQuery  string
 	rangeQuery   string
+	rangeUnit    string
 	countQuery   string
 	singleResult bool
+	omitZero     bool
+	readOnly     bool
 	headers      map[string]string
 	joins        []join
-	rawQuery     string
-	method       string
-	client       *Client
+	embeds       []string
+	// embedBuilders are embedded resources with their own columns and filters, see Embed
+	embedBuilders []*EmbedBuilder
+	// foreignOrders order resources embedded through Select, keyed by path, see OrderForeign
+	foreignOrders []rawParam
+	rawQuery      string
+	// function and functionArgs read the rows from a server function, see WhereWithinDistance
+	function     string
+	functionArgs []rawParam
+	// procedure receives a write in place of the table, with the filters in its
+	// arguments, see UpdateJSON
+	procedure string
+	// distanceOrder keeps the function's nearest-first order, see OrderByDistance
+	distanceOrder bool
+	// scalar leaves out the client's default order, see GetScalar
+	scalar bool
+	// rawParams are sent as given after the modeled parameters, see RawParam
+	rawParams  []rawParam
+	onConflict string
+	// requireAtomic rejects operations that take several requests, see RequireAtomic
+	requireAtomic bool
+	// fullRows lets BulkUpdate upsert, see FullRows
+	fullRows bool
+	// noCache and cacheTTL override the client's query cache, see WithQueryCache
+	noCache  bool
+	cacheTTL time.Duration
+	// writeResult receives the response status and body, see CaptureResult
+	writeResult *WriteResult
+	// retryWrites lets WithRetry retry the query when it is a write
+	retryWrites bool
+	// timeout replaces the client's WithHTTPTimeout for this query, see Timeout
+	timeout time.Duration
+	// headersOnly makes Insert read the new row's key from Location, see ReturnHeadersOnly
+	headersOnly bool
+	tx          *Transaction
+	method      string
+	ctx         context.Context
+	errs        []error
+	// allowedColumns restricts the columns the query may reference, see RestrictColumns
+	allowedColumns map[string]bool
+	client         *Client
 }
 
 // NewQueryBuilder creates a new QueryBuilder for the specified table
@@ -41,179 +88,1587 @@ func NewQueryBuilder(table string) *QueryBuilder {
 
 // NewClient creates a new Supabase client with the given URL and API key
 func NewClient(baseURL, apiKey string) *Client {
-	return &Client{
-		baseURL: baseURL,
-		apiKey:  apiKey,
+	return New(baseURL, apiKey)
+}
+
+// From creates a new QueryBuilder for the specified table.
+// It is an alias for Table; each call returns an independent builder.
+func (c *Client) From(table string) *QueryBuilder {
+	return c.Table(table)
+}
+
+// RPC calls a stored procedure
+// The function is resolved in the client's schema, see Client.Schema.
+func (c *Client) RPC(procedure string, params map[string]interface{}, result interface{}) error {
+	endpoint := fmt.Sprintf("%s/rest/v1/rpc/%s", c.GetBaseURL(), procedure)
+
+	req := c.RawRequest().SetBody(params)
+	if c.schema != "" {
+		req.SetHeader("Accept-Profile", c.schema)
+		req.SetHeader("Content-Profile", c.schema)
+	}
+
+	resp, err := req.Post(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return newAPIError(resp)
+	}
+
+	if result != nil && len(resp.Body()) > 0 {
+		return json.Unmarshal(resp.Body(), result)
+	}
+
+	return nil
+}
+
+// RPCRaw calls a stored procedure whose result is not JSON, such as a report returned
+// as CSV, and returns the response body unchanged. accept is sent as the Accept header,
+// e.g. "text/csv" or "text/plain", and the function must produce that media type.
+func (c *Client) RPCRaw(ctx context.Context, procedure string, params map[string]interface{}, accept string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/rest/v1/rpc/%s", c.GetBaseURL(), procedure)
+
+	req := c.RawRequest().SetContext(ctx).SetBody(params).SetHeader("Accept", accept)
+	if c.schema != "" {
+		req.SetHeader("Accept-Profile", c.schema)
+		req.SetHeader("Content-Profile", c.schema)
+	}
+
+	resp, err := req.Post(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, newAPIError(resp)
+	}
+
+	return resp.Body(), nil
+}
+
+// Range units accepted by RangeUnit
+const (
+	// RangeUnitItems paginates table rows
+	RangeUnitItems = "items"
+	// RangeUnitBytes addresses byte ranges, e.g. for storage downloads
+	RangeUnitBytes = "bytes"
+)
+
+// QueryBuilder builds and executes queries against the Supabase API
+
+type filter struct {
+	column    string
+	operator  string
+	value     interface{}
+	isOr      bool
+	isComplex bool
+}
+
+type order struct {
+	column    string
+	direction string
+}
+
+type rangeQuery struct {
+	start int
+	end   int
+}
+
+// rawParam is a query parameter added with RawParam
+type rawParam struct {
+	key   string
+	value string
+}
+
+type join struct {
+	foreignTable  string
+	localColumn   string
+	operator      string
+	foreignColumn string
+}
+
+// Select specifies the columns to return
+func (q *QueryBuilder) Select(columns ...string) *QueryBuilder {
+	q.selectQuery = strings.Join(quoteColumns(columns), ",")
+	return q
+}
+
+// SelectJSONAs selects a nested json field as a named column, e.g.
+// SelectJSONAs("tier", "metadata", "tier") emits tier:metadata->>tier.
+// Nested keys are separated by "->" in path, e.g. "settings->theme".
+func (q *QueryBuilder) SelectJSONAs(alias, column, path string) *QueryBuilder {
+	if !identifierPattern.MatchString(alias) {
+		q.errs = append(q.errs, fmt.Errorf("invalid alias %q", alias))
+		return q
+	}
+	if !identifierPattern.MatchString(column) {
+		q.errs = append(q.errs, fmt.Errorf("invalid json column %q", column))
+		return q
+	}
+
+	keys := strings.Split(path, "->")
+	for _, key := range keys {
+		if !jsonKeyPattern.MatchString(key) {
+			q.errs = append(q.errs, fmt.Errorf("invalid json path %q", path))
+			return q
+		}
+	}
+
+	// Intermediate keys keep the json type, the last one is extracted as text
+	expr := column
+	for _, key := range keys[:len(keys)-1] {
+		expr += "->" + key
+	}
+	expr += "->>" + keys[len(keys)-1]
+
+	return q.addSelectColumn(alias + ":" + expr)
+}
+
+// addSelectColumn appends a column to the explicit select list
+func (q *QueryBuilder) addSelectColumn(column string) *QueryBuilder {
+	if q.selectQuery == "" {
+		q.selectQuery = column
+	} else {
+		q.selectQuery += "," + column
+	}
+	return q
+}
+
+// SelectCount embeds the number of related rows in foreignTable, e.g. posts(count).
+// The count is returned as [{"count":n}] and can be decoded into an EmbeddedCount field.
+func (q *QueryBuilder) SelectCount(foreignTable string) *QueryBuilder {
+	q.embeds = append(q.embeds, fmt.Sprintf("%s(count)", foreignTable))
+	return q
+}
+
+// SelectSpread embeds foreignTable with the spread operator, flattening its columns
+// onto the parent row, e.g. SelectSpread("posts", "count") emits ...posts(count)
+// and the count decodes into a top-level int field tagged json:"count".
+func (q *QueryBuilder) SelectSpread(foreignTable string, columns ...string) *QueryBuilder {
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+	q.embeds = append(q.embeds, fmt.Sprintf("...%s(%s)", foreignTable, strings.Join(columns, ",")))
+	return q
+}
+
+// EmbedWithFK embeds foreignTable through the named foreign key or column, for tables
+// related by more than one foreign key, under alias so that the embeds decode into
+// separate fields, e.g. EmbedWithFK("author", "users", "author_id", "name") emits
+// author:users!author_id(name). An empty alias keys the rows by the table name, which
+// two embeds of the same table would share. Columns default to *.
+func (q *QueryBuilder) EmbedWithFK(alias, foreignTable, fkName string, columns ...string) *QueryBuilder {
+	if alias != "" && !identifierPattern.MatchString(alias) {
+		q.errs = append(q.errs, fmt.Errorf("invalid embed alias %q", alias))
+		return q
+	}
+	if !identifierPattern.MatchString(fkName) {
+		q.errs = append(q.errs, fmt.Errorf("invalid foreign key hint %q", fkName))
+		return q
+	}
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+	if alias != "" {
+		alias += ":"
+	}
+	q.embeds = append(q.embeds, fmt.Sprintf("%s%s!%s(%s)", alias, foreignTable, fkName, strings.Join(columns, ",")))
+	return q
+}
+
+// buildSelect combines the selected columns with joins and embeds
+func (q *QueryBuilder) buildSelect() string {
+	columns := q.selectQuery
+
+	var embeds []string
+	for _, j := range q.joins {
+		// Format: foreignTable(*)
+		embeds = append(embeds, fmt.Sprintf("%s(*)", j.foreignTable))
+	}
+	embeds = append(embeds, q.embeds...)
+	for _, e := range q.embedBuilders {
+		embeds = append(embeds, e.selectClause())
+	}
+
+	if len(embeds) == 0 {
+		return columns
+	}
+
+	// Select all columns from the main table when only embeds were requested
+	if columns == "" {
+		columns = "*"
+	}
+
+	return columns + "," + strings.Join(embeds, ",")
+}
+
+// Where adds a filter condition
+func (q *QueryBuilder) Where(column, operator string, value interface{}) *QueryBuilder {
+	q.filters = append(q.filters, fmt.Sprintf("%s=%s.%s", quoteColumn(column), operator, formatOperand(operator, value)))
+	return q
+}
+
+// WhereCast filters on column cast to castType, e.g. WhereCast("price", "numeric", "gt", 10)
+// emits price::numeric=gt.10 to compare a text column numerically
+func (q *QueryBuilder) WhereCast(column, castType, operator string, value interface{}) *QueryBuilder {
+	if !castTypePattern.MatchString(castType) {
+		q.errs = append(q.errs, fmt.Errorf("invalid cast type %q", castType))
+		return q
+	}
+	return q.Where(column+"::"+castType, operator, value)
+}
+
+// WhereIn filters rows where column matches any of values, e.g. status=in.(active,pending).
+// String values containing commas, quotes or parentheses are quoted and escaped.
+func (q *QueryBuilder) WhereIn(column string, values ...interface{}) *QueryBuilder {
+	return q.Where(column, "in", values)
+}
+
+// WhereNotIn filters rows where column matches none of values, e.g. status=not.in.(banned)
+func (q *QueryBuilder) WhereNotIn(column string, values ...interface{}) *QueryBuilder {
+	return q.Not(column, "in", values)
+}
+
+// WhereDistinctFrom filters rows where column IS DISTINCT FROM value, e.g.
+// status=isdistinct.archived. Unlike neq it keeps rows where column is null, and a nil
+// value emits status=isdistinct.null, matching the rows where column is not null.
+func (q *QueryBuilder) WhereDistinctFrom(column string, value interface{}) *QueryBuilder {
+	if value == nil {
+		value = "null"
+	}
+	return q.Where(column, "isdistinct", value)
+}
+
+// WhereIs filters on column with the is operator, which compares by identity instead of
+// equality: true, false and nil emit is.true, is.false and is.null, and a nil *bool is
+// null. Unlike Where(column, "eq", true), is.false also excludes null. The strings
+// "true", "false", "null" and "unknown" are accepted as well.
+func (q *QueryBuilder) WhereIs(column string, value interface{}) *QueryBuilder {
+	if p, ok := value.(*bool); ok {
+		value = nil
+		if p != nil {
+			value = *p
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		value = "null"
+	case bool:
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "false", "null", "unknown":
+			value = strings.ToLower(v)
+		default:
+			q.errs = append(q.errs, fmt.Errorf("invalid is value %q for %s", v, column))
+			return q
+		}
+	default:
+		q.errs = append(q.errs, fmt.Errorf("invalid is value %v for %s, want a bool or nil", value, column))
+		return q
+	}
+
+	return q.Where(column, "is", value)
+}
+
+// WhereEqAny filters rows where column equals any of values, e.g. tag=eq(any).{go,rust}.
+// Values are sent as an array literal, with strings quoted where needed.
+func (q *QueryBuilder) WhereEqAny(column string, values ...interface{}) *QueryBuilder {
+	return q.Where(column, "eq(any)", formatArray(values))
+}
+
+// WhereEqAll filters rows where column equals all of values, e.g. tag=eq(all).{go},
+// which only holds when every value is the same
+func (q *QueryBuilder) WhereEqAll(column string, values ...interface{}) *QueryBuilder {
+	return q.Where(column, "eq(all)", formatArray(values))
+}
+
+// TextSearch filters rows whose tsvector column matches query, written in the web
+// search syntax of websearch_to_tsquery, e.g. `"exact phrase" -excluded or other`
+func (q *QueryBuilder) TextSearch(column, query string) *QueryBuilder {
+	return q.Where(column, "wfts", query)
+}
+
+// OrderByRank searches the tsvector column for query, as TextSearch does, and sorts
+// the matches by relevance, highest first. PostgREST can't call ts_rank in an order,
+// so the rank is read from a column named after the searched one, <column>_rank:
+// either a precomputed rank column or a computed column function exposed by the
+// server. Rows without a rank sort last. An empty query only orders the rows.
+func (q *QueryBuilder) OrderByRank(column, query string) *QueryBuilder {
+	if query != "" {
+		q.TextSearch(column, query)
+	}
+	return q.Order(column+"_rank", "desc.nullslast")
+}
+
+// WhereStruct adds a filter for each set field of a query-by-example struct. Fields are
+// mapped with a supabase:"column,operator" tag, the operator defaulting to eq, e.g.
+//
+//	type UserSearch struct {
+//		Name   string   `supabase:"name,ilike"`
+//		MinAge int      `supabase:"age,gte"`
+//		Status []string `supabase:"status,in"`
+//		Active *bool    `supabase:"active"`
+//	}
+//
+// Zero fields and nil pointers are skipped; use a pointer to filter on a zero value.
+// Fields without a supabase tag are ignored, as is the omitzero option, so the same
+// struct can carry supabase:"age,gte,omitzero" for Update.
+func (q *QueryBuilder) WhereStruct(v interface{}) *QueryBuilder {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		q.errs = append(q.errs, fmt.Errorf("WhereStruct expects a struct, got %T", v))
+		return q
+	}
+
+	for _, field := range reflect.VisibleFields(value.Type()) {
+		column, operator := parseFilterTag(field.Tag.Get("supabase"))
+		if !field.IsExported() || field.Anonymous || column == "" || column == "-" {
+			continue
+		}
+
+		// Fields promoted through a nil embedded pointer are unset
+		fieldValue, err := value.FieldByIndexErr(field.Index)
+		if err != nil || fieldValue.IsZero() {
+			continue
+		}
+		if fieldValue.Kind() == reflect.Ptr {
+			fieldValue = fieldValue.Elem()
+		}
+
+		q.Where(column, operator, fieldValue.Interface())
+	}
+
+	return q
+}
+
+// OrWhere adds an OR filter condition
+func (q *QueryBuilder) OrWhere(column, operator string, value interface{}) *QueryBuilder {
+	q.filters = append(q.filters, fmt.Sprintf("and=(or(%s.%s.%s))", quoteColumn(column), operator, formatOperand(operator, value)))
+	return q
+}
+
+// WhereJSONArrayLength filters on the number of elements in a jsonb array, e.g.
+// WhereJSONArrayLength("tags", "gte", 3). The column may locate the array with a ->
+// path and end in a cast applied to the compared elements, e.g. metadata->tags::text.
+// PostgREST can't call jsonb_array_length in a filter, so the comparison is expressed
+// through the -> path operator: an array has at least n elements when element n-1
+// exists. Rows whose value is null or not an array never match. Supported operators are
+// eq, neq, gt, gte, lt and lte; for anything more involved, expose a computed column or
+// an RPC function that returns jsonb_array_length(column).
+func (q *QueryBuilder) WhereJSONArrayLength(column string, operator string, n int) *QueryBuilder {
+	path, cast, hasCast := strings.Cut(column, "::")
+	keys := strings.Split(path, "->")
+	if !identifierPattern.MatchString(keys[0]) || (hasCast && !castTypePattern.MatchString(cast)) {
+		q.errs = append(q.errs, fmt.Errorf("invalid json array column %q", column))
+		return q
+	}
+	for _, key := range keys[1:] {
+		if !jsonKeyPattern.MatchString(key) {
+			q.errs = append(q.errs, fmt.Errorf("invalid json array column %q", column))
+			return q
+		}
+	}
+	if n < 0 {
+		q.errs = append(q.errs, fmt.Errorf("invalid json array length %d for %s", n, column))
+		return q
+	}
+
+	keys[0] = quoteColumn(keys[0])
+	array := strings.Join(keys, "->")
+	if hasCast {
+		cast = "::" + cast
+	}
+
+	// element returns the k-th element of the array, cast like the column
+	element := func(k int) string {
+		return fmt.Sprintf("%s->%d%s", array, k, cast)
+	}
+
+	var conditions []string
+	switch operator {
+	case "gte", ">=":
+		if n > 0 {
+			conditions = append(conditions, element(n-1)+"=not.is.null")
+		}
+	case "gt", ">":
+		conditions = append(conditions, element(n)+"=not.is.null")
+	case "lt", "<":
+		if n == 0 {
+			q.errs = append(q.errs, fmt.Errorf("json array length of %s can never be less than 0", column))
+			return q
+		}
+		conditions = append(conditions, element(n-1)+"=is.null")
+	case "lte", "<=":
+		conditions = append(conditions, element(n)+"=is.null")
+	case "eq", "=":
+		if n > 0 {
+			conditions = append(conditions, element(n-1)+"=not.is.null")
+		}
+		conditions = append(conditions, element(n)+"=is.null")
+	case "neq", "!=", "<>":
+		if n == 0 {
+			conditions = append(conditions, element(0)+"=not.is.null")
+		} else {
+			conditions = append(conditions, fmt.Sprintf("or=(%s.is.null,%s.not.is.null)", element(n-1), element(n)))
+		}
+	default:
+		q.errs = append(q.errs, fmt.Errorf("unsupported operator %q for json array length", operator))
+		return q
+	}
+
+	// Missing elements are null for null columns and other json values too, so only
+	// arrays, which contain the empty array, are kept
+	q.filters = append(q.filters, array+"=cs.[]")
+	q.filters = append(q.filters, conditions...)
+	return q
+}
+
+// WhereRaw adds a raw filter condition
+func (q *QueryBuilder) WhereRaw(condition string) *QueryBuilder {
+	q.filters = append(q.filters, fmt.Sprintf("and=(and(%s))", condition))
+	return q
+}
+
+// Order adds an order clause
+func (q *QueryBuilder) Order(column, direction string) *QueryBuilder {
+	q.orderQuery = fmt.Sprintf("order=%s.%s", quoteColumn(column), direction)
+	return q
+}
+
+// OrderSafe sorts by column in a direction taken from user input, e.g. a dir query
+// parameter. Only "asc" and "desc" are accepted, ignoring case; anything else sorts
+// ascending, so the direction can't inject order modifiers or other terms. The column
+// must be a plain identifier and, when RestrictColumns is set, one of the allowed columns.
+func (q *QueryBuilder) OrderSafe(column, direction string) *QueryBuilder {
+	if !identifierPattern.MatchString(column) {
+		q.errs = append(q.errs, fmt.Errorf("invalid order column %q", column))
+		return q
+	}
+
+	if !strings.EqualFold(direction, "desc") {
+		direction = "asc"
+	}
+
+	return q.Order(column, strings.ToLower(direction))
+}
+
+// RawParam adds a query parameter that is sent as given, for PostgREST features the
+// builder doesn't model yet. The key and value are URL-encoded, but not validated:
+// Validate and RestrictColumns don't see them, so never pass untrusted input.
+func (q *QueryBuilder) RawParam(key, value string) *QueryBuilder {
+	q.rawParams = append(q.rawParams, rawParam{key: key, value: value})
+	return q
+}
+
+// Limit sets the maximum number of rows to return.
+// Limit(0) is sent as limit=0 and returns no rows, to check a query's shape or fetch only its count.
+func (q *QueryBuilder) Limit(limit int) *QueryBuilder {
+	q.limitQuery = fmt.Sprintf("limit=%d", limit)
+	return q
+}
+
+// effectiveOrder returns the order sent with the query: the explicit order, or the
+// client's default order on reads of rows. Counts, scalar reads and aggregates don't get
+// the default, since Postgres rejects ordering grouped rows by an ungrouped column.
+// Paginating reads without an order are logged as a warning, since their pages can
+// repeat or skip rows.
+func (q *QueryBuilder) effectiveOrder() string {
+	if q.orderQuery != "" {
+		return strings.TrimPrefix(q.orderQuery, "order=")
+	}
+
+	if q.distanceOrder || q.scalar || q.client == nil || q.method != http.MethodGet || q.selectsAggregate() {
+		return ""
+	}
+
+	if q.client.defaultOrder == "" && (q.rangeQuery != "" || q.offsetQuery != "") && q.client.logger != nil {
+		q.client.logger.Warn("paginating without an order, pages may repeat or skip rows", "table", q.table)
+	}
+
+	return q.client.defaultOrder
+}
+
+// selectsAggregate reports whether the query's own columns include an aggregate;
+// aggregates inside embedded resources don't group the top-level rows
+func (q *QueryBuilder) selectsAggregate() bool {
+	for _, item := range splitGroup(q.selectQuery) {
+		// An embedded resource, e.g. posts(amount.sum()), opens its parentheses first
+		if aggregatePattern.MatchString(item) && strings.Index(item, "(") == strings.Index(item, "()") {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveLimit returns the limit sent with the query. Reads on a client with
+// WithMaxLimit get the maximum when no limit is set or the limit is above it.
+func (q *QueryBuilder) effectiveLimit() string {
+	limit := strings.TrimPrefix(q.limitQuery, "limit=")
+
+	if q.client == nil || q.client.maxLimit <= 0 || (q.method != http.MethodGet && q.method != http.MethodHead) {
+		return limit
+	}
+
+	if n, err := strconv.Atoi(limit); err != nil || n > q.client.maxLimit {
+		return strconv.Itoa(q.client.maxLimit)
+	}
+
+	return limit
+}
+
+// Offset sets the number of rows to skip
+func (q *QueryBuilder) Offset(offset int) *QueryBuilder {
+	q.offsetQuery = fmt.Sprintf("offset=%d", offset)
+	return q
+}
+
+// Range sets the range of rows to return
+// Table reads use the "items" unit unless RangeUnit says otherwise.
+func (q *QueryBuilder) Range(start, end int) *QueryBuilder {
+	q.rangeQuery = fmt.Sprintf("range=%d-%d", start, end)
+	return q
+}
+
+// RangeUnit sets the unit sent in the Range-Unit header, e.g. "items" or "bytes"
+func (q *QueryBuilder) RangeUnit(unit string) *QueryBuilder {
+	q.rangeUnit = unit
+	return q
+}
+
+// rangeHeaders returns the Range and Range-Unit header values for the query
+func (q *QueryBuilder) rangeHeaders() (string, string) {
+	unit := q.rangeUnit
+	if unit == "" {
+		unit = RangeUnitItems
+	}
+
+	bounds := strings.TrimPrefix(q.rangeQuery, "range=")
+	if unit == RangeUnitBytes {
+		// Byte ranges use the standard HTTP form
+		return "bytes=" + bounds, unit
+	}
+
+	return bounds, unit
+}
+
+// IfMatch makes the request conditional on the resource matching the given ETag
+func (q *QueryBuilder) IfMatch(etag string) *QueryBuilder {
+	return q.Header("If-Match", etag)
+}
+
+// IfNoneMatch makes the request conditional on the resource not matching the given ETag.
+// When it still matches, the request fails with ErrNotModified.
+func (q *QueryBuilder) IfNoneMatch(etag string) *QueryBuilder {
+	return q.Header("If-None-Match", etag)
+}
+
+// Header adds a custom header to the request
+func (q *QueryBuilder) Header(key, value string) *QueryBuilder {
+	if q.headers == nil {
+		q.headers = make(map[string]string)
+	}
+	q.headers[key] = value
+	return q
+}
+
+// WithContext sets the context used for the request. When it has a deadline, the
+// remaining time is also sent as Prefer: timeout=<seconds>, so PostgREST sets the
+// statement timeout and Postgres stops a query whose caller has given up on it.
+func (q *QueryBuilder) WithContext(ctx context.Context) *QueryBuilder {
+	q.ctx = ctx
+	return q
+}
+
+// Timeout sets the time the query may take, replacing the client's WithHTTPTimeout,
+// whether shorter or longer. The context's deadline still wins when it comes sooner.
+func (q *QueryBuilder) Timeout(d time.Duration) *QueryBuilder {
+	q.timeout = d
+	return q
+}
+
+// requestContext returns the context the request is sent with: the query's context,
+// bounded by the query's Timeout or else the client's WithHTTPTimeout
+func (q *QueryBuilder) requestContext() (context.Context, context.CancelFunc) {
+	timeout := q.timeout
+	if timeout <= 0 && q.client != nil {
+		timeout = q.client.httpTimeout
+	}
+	if timeout <= 0 {
+		return q.ctx, func() {}
+	}
+
+	ctx := q.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutPreference returns the timeout preference for the deadline of ctx, in whole
+// seconds rounded up, or an empty string when ctx has no deadline
+func timeoutPreference(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ""
+	}
+
+	seconds := int(math.Ceil(time.Until(deadline).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return fmt.Sprintf("timeout=%d", seconds)
+}
+
+// RequireAtomic makes operations that take several requests, such as a BatchInsert
+// with more than one chunk or InsertIfNotExists, fail with ErrNotAtomic instead of
+// committing part of their work
+func (q *QueryBuilder) RequireAtomic() *QueryBuilder {
+	q.requireAtomic = true
+	return q
+}
+
+// FullRows declares that the rows given to BulkUpdate are complete objects, carrying
+// every required column, so that it may send them as one upsert
+func (q *QueryBuilder) FullRows() *QueryBuilder {
+	q.fullRows = true
+	return q
+}
+
+// prefer adds a preference to the Prefer header, keeping those already set
+func (q *QueryBuilder) prefer(preference string) *QueryBuilder {
+	if current := q.headers["Prefer"]; current != "" {
+		preference = current + ", " + preference
+	}
+	return q.Header("Prefer", preference)
+}
+
+// MaxAffected makes an update or delete fail with ErrTooManyAffected, without changing
+// any row, when it would affect more than n rows. It requires PostgREST 12 or later;
+// when the server version is known, older servers fail with ErrUnsupportedFeature.
+func (q *QueryBuilder) MaxAffected(n int) *QueryBuilder {
+	return q.prefer(fmt.Sprintf("handling=strict, max-affected=%d", n))
+}
+
+// Join adds a join clause to the query
+// This uses the PostgREST foreign key join syntax
+func (q *QueryBuilder) Join(foreignTable, localColumn, operator, foreignColumn string) *QueryBuilder {
+	q.joins = append(q.joins, join{
+		foreignTable:  foreignTable,
+		localColumn:   localColumn,
+		operator:      operator,
+		foreignColumn: foreignColumn,
+	})
+	return q
+}
+
+// InnerJoin is a convenience method for Join with "eq" operator
+func (q *QueryBuilder) InnerJoin(foreignTable, localColumn, foreignColumn string) *QueryBuilder {
+	return q.Join(foreignTable, localColumn, "eq", foreignColumn)
+}
+
+// LeftJoin is a convenience method for left join
+// Note: PostgREST doesn't directly support LEFT JOIN, but we can emulate it
+func (q *QueryBuilder) LeftJoin(foreignTable, localColumn, foreignColumn string) *QueryBuilder {
+	// Add the join
+	q.Join(foreignTable, localColumn, "eq", foreignColumn)
+
+	// Set the Prefer header to include nulls
+	q.prefer("missing=null")
+
+	return q
+}
+
+// Raw sets a raw SQL query to be executed
+// This uses the PostgREST RPC function call mechanism
+func (q *QueryBuilder) Raw(query string) *QueryBuilder {
+	q.rawQuery = query
+	return q
+}
+
+// Get executes the query and returns the results
+func (q *QueryBuilder) Get(result interface{}) error {
+	return q.execute(nil, result)
+}
+
+// First executes the query and returns the first result
+func (q *QueryBuilder) First(result interface{}) error {
+	q.Limit(1)
+	return q.execute(nil, result)
+}
+
+// Page is the envelope returned by GetPaginated. It marshals to
+// {"data":...,"total":...,"page":...,"pageSize":...,"hasMore":...} for frontends.
+type Page struct {
+	Data     interface{} `json:"data"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"pageSize"`
+	HasMore  bool        `json:"hasMore"`
+}
+
+// GetPaginated fetches one page of rows into result, along with the total count.
+// The count is exact unless another mode was chosen with CountMode.
+// Pages are numbered from 1; page 2 with a pageSize of 20 reads rows 20-39.
+func (q *QueryBuilder) GetPaginated(ctx context.Context, page, pageSize int, result interface{}) (*Page, error) {
+	if page < 1 || pageSize < 1 {
+		return nil, fmt.Errorf("invalid page %d with size %d", page, pageSize)
+	}
+
+	from := (page - 1) * pageSize
+	q.ctx = ctx
+	q.Range(from, from+pageSize-1)
+	if q.countQuery == "" {
+		q.Count()
+	}
+
+	resp, err := q.send(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.decode(resp, result); err != nil {
+		return nil, err
+	}
+
+	total, err := contentRangeTotal(resp.Header().Get("Content-Range"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{
+		Data:     result,
+		Total:    int(total),
+		Page:     page,
+		PageSize: pageSize,
+		HasMore:  int64(from+pageSize) < total,
+	}, nil
+}
+
+// GetWithCount executes the query, decodes the rows into result when it is non-nil, and
+// returns the total parsed from the Content-Range header. An exact count is requested
+// unless another mode was chosen with CountMode; combine with Limit(0) to fetch only the count.
+// It returns ErrCountUnavailable when the response carries no total.
+func (q *QueryBuilder) GetWithCount(ctx context.Context, result interface{}) (int64, error) {
+	q.ctx = ctx
+	if q.countQuery == "" {
+		q.Count()
+	}
+
+	resp, err := q.send(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := q.decode(resp, result); err != nil {
+		return 0, err
+	}
+
+	return contentRangeTotal(resp.Header().Get("Content-Range"))
+}
+
+// CountContext returns the number of rows matching the query's filters. It sends a
+// HEAD request, so no rows are transferred and the total is read from Content-Range.
+// The count is exact unless another mode was set with CountMode. It returns
+// ErrCountUnavailable when the response carries no total.
+func (q *QueryBuilder) CountContext(ctx context.Context) (int64, error) {
+	q.method = http.MethodHead
+	q.ctx = ctx
+	if q.countQuery == "" {
+		q.Count()
+	}
+
+	resp, err := q.send(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return contentRangeTotal(resp.Header().Get("Content-Range"))
+}
+
+// DistinctValues decodes the distinct non-null values of column into dest, e.g. a
+// *[]string of statuses for a filter dropdown. PostgREST has no DISTINCT, so the column
+// is fetched for every matching row and deduplicated client-side, keeping the first
+// occurrence; combine with Order to sort the values and filters to narrow the rows.
+func (q *QueryBuilder) DistinctValues(ctx context.Context, column string, dest interface{}) error {
+	if !identifierPattern.MatchString(column) {
+		return fmt.Errorf("invalid column name %q", column)
+	}
+
+	q.ctx = ctx
+	q.Select(column)
+
+	var rows []map[string]json.RawMessage
+	if err := q.execute(nil, &rows); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(rows))
+	values := make([]json.RawMessage, 0, len(rows))
+	for _, row := range rows {
+		value, ok := row[column]
+		if !ok || string(value) == "null" || seen[string(value)] {
+			continue
+		}
+		seen[string(value)] = true
+		values = append(values, value)
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(encoded, dest)
+}
+
+// GetScalar executes a query that returns a single row with a single column and decodes
+// that value into dest, e.g. Select("age.max()").GetScalar(&maxAge) with dest an *int,
+// *string or *time.Time. It fails when the result has any other shape.
+func (q *QueryBuilder) GetScalar(dest interface{}) error {
+	q.scalar = true
+
+	var rows []map[string]json.RawMessage
+	if err := q.execute(nil, &rows); err != nil {
+		return err
+	}
+
+	if len(rows) != 1 {
+		return fmt.Errorf("scalar query returned %d rows, want 1", len(rows))
+	}
+
+	if len(rows[0]) != 1 {
+		return fmt.Errorf("scalar query returned %d columns, want 1", len(rows[0]))
+	}
+
+	for _, value := range rows[0] {
+		return json.Unmarshal(value, dest)
+	}
+
+	return nil
+}
+
+// Insert inserts a new record
+// When data is a pointer, the returned representation is decoded back into it,
+// or only the new row's key with ReturnHeadersOnly.
+func (q *QueryBuilder) Insert(data interface{}) error {
+	q.method = http.MethodPost
+
+	var result interface{}
+	if reflect.ValueOf(data).Kind() == reflect.Ptr {
+		result = data
+	}
+
+	body, err := q.beforeInsert(data)
+	if err != nil {
+		return err
+	}
+
+	if q.headersOnly {
+		return q.insertHeadersOnly(body, data)
+	}
+
+	return q.execute(body, result)
+}
+
+// ReturnHeadersOnly makes Insert ask for Prefer: return=headers-only, so no row is sent
+// back, and decode the primary key from the Location header into data instead, e.g.
+// a Location of /users?id=eq.42 sets the field of data whose column is id to 42.
+// Other fields keep the values that were sent. It only applies to single rows, since
+// PostgREST returns a Location for those alone.
+func (q *QueryBuilder) ReturnHeadersOnly() *QueryBuilder {
+	q.headersOnly = true
+	return q
+}
+
+// insertHeadersOnly sends a single-row insert and decodes the key in its Location into data
+func (q *QueryBuilder) insertHeadersOnly(body, data interface{}) error {
+	if v := reflect.Indirect(reflect.ValueOf(data)); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return fmt.Errorf("ReturnHeadersOnly on %s: inserting %d rows, Location identifies one", q.table, v.Len())
+	}
+
+	q.prefer("return=headers-only")
+	resp, err := q.send(body)
+	if err != nil {
+		return err
+	}
+
+	if reflect.ValueOf(data).Kind() != reflect.Ptr {
+		return nil
+	}
+	return q.decodeLocation(resp.Header().Get("Location"), data)
+}
+
+// decodeLocation decodes the column filters of a Location header, e.g. /users?id=eq.42,
+// into data. Values are decoded as JSON where they parse as such, e.g. numbers for
+// integer keys, and as strings otherwise, e.g. uuids.
+func (q *QueryBuilder) decodeLocation(location string, data interface{}) error {
+	if location == "" {
+		return fmt.Errorf("insert into %s: response has no Location header", q.table)
+	}
+
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("insert into %s: invalid Location %q: %w", q.table, location, err)
+	}
+
+	for column, values := range parsed.Query() {
+		value, ok := strings.CutPrefix(values[0], "eq.")
+		if !ok {
+			continue
+		}
+
+		var decoded interface{}
+		if json.Unmarshal([]byte(value), &decoded) != nil {
+			decoded = value
+		}
+		raw, _ := json.Marshal(map[string]interface{}{column: decoded})
+		if err := q.unmarshal(raw, data); err != nil {
+			// A key that parses as a number may still belong to a text column
+			raw, _ = json.Marshal(map[string]string{column: value})
+			if err := q.unmarshal(raw, data); err != nil {
+				return fmt.Errorf("insert into %s: decoding %s from Location: %w", q.table, column, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// InsertReturning inserts data and decodes the created rows into result.
+// Combine it with Select to return only some columns, e.g. the generated id.
+func (q *QueryBuilder) InsertReturning(data interface{}, result interface{}) error {
+	q.method = http.MethodPost
+	q.prefer("return=representation")
+
+	body, err := q.beforeInsert(data)
+	if err != nil {
+		return err
+	}
+
+	return q.execute(body, result)
+}
+
+// InsertIfNotExists inserts data unless a row with the same conflictColumns values exists,
+// which are the columns of a unique constraint. Either way the canonical row is decoded
+// back into data, which must be a pointer to a struct or map. It reports whether the row
+// was inserted. The insert and the lookup of an existing row are separate requests.
+func (q *QueryBuilder) InsertIfNotExists(data interface{}, conflictColumns ...string) (bool, error) {
+	if reflect.ValueOf(data).Kind() != reflect.Ptr {
+		return false, fmt.Errorf("InsertIfNotExists expects a pointer, got %T", data)
+	}
+
+	if len(conflictColumns) == 0 {
+		return false, fmt.Errorf("InsertIfNotExists on %s requires conflict columns", q.table)
+	}
+
+	if q.requireAtomic {
+		return false, fmt.Errorf("%w: InsertIfNotExists on %s inserts and reads in separate requests", ErrNotAtomic, q.table)
+	}
+
+	if _, err := q.beforeInsert(data); err != nil {
+		return false, err
+	}
+
+	values, ok := structToMap(data, false, q.client.columnNamer).(map[string]interface{})
+	if !ok {
+		// Maps are not converted by StructToMap
+		if err := remarshal(data, &values); err != nil {
+			return false, err
+		}
+	}
+
+	q.method = http.MethodPost
+	q.onConflict = strings.Join(conflictColumns, ",")
+	q.prefer("resolution=ignore-duplicates, return=representation")
+
+	var inserted []json.RawMessage
+	if err := q.execute(data, &inserted); err != nil {
+		return false, err
+	}
+
+	if len(inserted) > 0 {
+		return true, q.unmarshal(inserted[0], data)
+	}
+
+	// The insert was ignored: fetch the existing row by its conflict columns
+	existing := q.client.Table(q.table).WithContext(q.ctx)
+	for _, column := range conflictColumns {
+		value, ok := values[column]
+		if !ok {
+			return false, fmt.Errorf("InsertIfNotExists on %s: data has no %s value", q.table, column)
+		}
+		existing.Where(column, "eq", value)
+	}
+
+	var rows []json.RawMessage
+	if err := existing.Limit(1).execute(nil, &rows); err != nil {
+		return false, err
+	}
+
+	if len(rows) == 0 {
+		return false, fmt.Errorf("InsertIfNotExists on %s: conflicting row not visible", q.table)
+	}
+
+	return false, q.unmarshal(rows[0], data)
+}
+
+// OnConflict sets the columns of the unique constraint that Upsert resolves conflicts on.
+// Without it the primary key is used.
+func (q *QueryBuilder) OnConflict(columns ...string) *QueryBuilder {
+	q.onConflict = strings.Join(columns, ",")
+	return q
+}
+
+// Upsert inserts data, merging it into the existing rows that conflict with it.
+// When data is a pointer the merged rows, including columns set by the server such
+// as defaults, generated columns and trigger-updated timestamps, are decoded back
+// into it: a slice receives its rows in order, a struct or map the first row.
+func (q *QueryBuilder) Upsert(data interface{}) error {
+	q.method = http.MethodPost
+	q.prefer("resolution=merge-duplicates, return=representation")
+
+	body, err := q.beforeInsert(data)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return q.execute(body, nil)
+	}
+
+	switch v.Elem().Kind() {
+	case reflect.Slice, reflect.Array:
+		return q.execute(body, data)
+	}
+
+	var rows []json.RawMessage
+	if err := q.execute(body, &rows); err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	return q.unmarshal(rows[0], data)
+}
+
+// remarshal converts src into dst through its json encoding
+func remarshal(src, dst interface{}) error {
+	encoded, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, dst)
+}
+
+// BatchInsertResult reports the outcome of a chunked BatchInsert
+type BatchInsertResult struct {
+	// Inserted is the number of rows inserted by the successful chunks
+	Inserted int
+	// FailedChunk is the zero-based index of the chunk that failed, or -1
+	FailedChunk int
+	// Rows holds the representations returned for the successful chunks
+	Rows []json.RawMessage
+}
+
+// BatchInsert inserts the rows of a slice in chunks of chunkSize, one request per chunk.
+// It stops at the first failing chunk and returns the partial result with the error,
+// so an import can resume from Inserted.
+func (q *QueryBuilder) BatchInsert(rows interface{}, chunkSize int) (*BatchInsertResult, error) {
+	result := &BatchInsertResult{FailedChunk: -1}
+
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return result, fmt.Errorf("batch insert requires a slice, got %T", rows)
+	}
+	if chunkSize <= 0 {
+		return result, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	if q.requireAtomic && v.Len() > chunkSize {
+		return result, fmt.Errorf("%w: %d rows take several requests with chunks of %d", ErrNotAtomic, v.Len(), chunkSize)
+	}
+
+	prepared, err := q.beforeInsert(rows)
+	if err != nil {
+		return result, err
+	}
+	v = reflect.Indirect(reflect.ValueOf(prepared))
+
+	for start, chunk := 0, 0; start < v.Len(); start, chunk = start+chunkSize, chunk+1 {
+		end := start + chunkSize
+		if end > v.Len() {
+			end = v.Len()
+		}
+
+		var returned []json.RawMessage
+		builder := q.clone()
+		builder.method = http.MethodPost
+		builder.prefer("return=representation")
+
+		if err := builder.execute(v.Slice(start, end).Interface(), &returned); err != nil {
+			result.FailedChunk = chunk
+			return result, fmt.Errorf("chunk %d: %w", chunk, err)
+		}
+
+		result.Inserted += end - start
+		result.Rows = append(result.Rows, returned...)
+	}
+
+	return result, nil
+}
+
+// BulkUpdate updates each row identified by keyColumn with its own values and returns
+// the number of rows affected. By default each row is sent as its own PATCH filtered by
+// its key, combined with the query's filters, so a key matching no row changes nothing;
+// the PATCHes stop at the first error and are rejected by RequireAtomic.
+//
+// With FullRows, rows that all have the same columns are instead sent in a single upsert
+// on keyColumn, which is the preferred path: one request, and atomic. An upsert inserts
+// rows whose key doesn't exist yet, which is why it needs complete rows, and those
+// inserts are counted as affected. The query can't have filters then, since an insert
+// ignores them.
+func (q *QueryBuilder) BulkUpdate(ctx context.Context, keyColumn string, rows []map[string]interface{}) (int, error) {
+	if !identifierPattern.MatchString(keyColumn) {
+		return 0, fmt.Errorf("invalid key column %q", keyColumn)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	uniform := true
+	for i, row := range rows {
+		if _, ok := row[keyColumn]; !ok {
+			return 0, fmt.Errorf("BulkUpdate on %s: row %d has no %s value", q.table, i, keyColumn)
+		}
+		if !sameColumns(rows[0], row) {
+			uniform = false
+		}
+	}
+
+	if uniform && q.fullRows {
+		if len(q.allFilters()) > 0 {
+			return 0, fmt.Errorf("BulkUpdate on %s: filters don't apply to the upsert of full rows", q.table)
+		}
+
+		builder := q.clone()
+		builder.ctx = ctx
+		builder.method = http.MethodPost
+		builder.onConflict = keyColumn
+		builder.prefer("resolution=merge-duplicates, return=representation")
+
+		var returned []json.RawMessage
+		if err := builder.execute(rows, &returned); err != nil {
+			return 0, err
+		}
+		return len(returned), nil
 	}
-}
 
-// From creates a new QueryBuilder for the specified table
-func (c *Client) From(table string) *QueryBuilder {
-	qb := NewQueryBuilder(table)
-	// Additional client-specific setup can go here
-	return qb
-}
+	if q.requireAtomic && len(rows) > 1 {
+		return 0, fmt.Errorf("%w: BulkUpdate on %s patches %d rows separately", ErrNotAtomic, q.table, len(rows))
+	}
 
-// RPC calls a stored procedure
-func (c *Client) RPC(procedure string, params map[string]interface{}, result interface{}) error {
-	// Implementation would go here
-	return nil
-}
+	affected := 0
+	for i, row := range rows {
+		patch := make(map[string]interface{}, len(row)-1)
+		for column, value := range row {
+			if column != keyColumn {
+				patch[column] = value
+			}
+		}
 
-// QueryBuilder builds and executes queries against the Supabase API
+		builder := q.clone()
+		builder.ctx = ctx
+		builder.method = http.MethodPatch
+		builder.prefer("return=representation")
+		builder.Where(keyColumn, "eq", row[keyColumn])
 
-type filter struct {
-	column    string
-	operator  string
-	value     interface{}
-	isOr      bool
-	isComplex bool
-}
+		var returned []json.RawMessage
+		if err := builder.execute(patch, &returned); err != nil {
+			return affected, fmt.Errorf("row %d: %w", i, err)
+		}
+		affected += len(returned)
+	}
 
-type order struct {
-	column    string
-	direction string
+	return affected, nil
 }
 
-type rangeQuery struct {
-	start int
-	end   int
+// sameColumns reports whether two rows have the same set of columns
+func sameColumns(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for column := range a {
+		if _, ok := b[column]; !ok {
+			return false
+		}
+	}
+	return true
 }
 
-type join struct {
-	foreignTable  string
-	localColumn   string
-	operator      string
-	foreignColumn string
+// clone returns a copy of the builder that shares no mutable state with it
+func (q *QueryBuilder) clone() *QueryBuilder {
+	c := *q
+	c.filters = append([]string(nil), q.filters...)
+	c.orFilters = append([]string(nil), q.orFilters...)
+	c.andFilters = append([]string(nil), q.andFilters...)
+	c.notFilters = append([]string(nil), q.notFilters...)
+	c.joins = append([]join(nil), q.joins...)
+	c.embeds = append([]string(nil), q.embeds...)
+	c.rawParams = append([]rawParam(nil), q.rawParams...)
+	c.functionArgs = append([]rawParam(nil), q.functionArgs...)
+	c.foreignOrders = append([]rawParam(nil), q.foreignOrders...)
+	c.embedBuilders = make([]*EmbedBuilder, len(q.embedBuilders))
+	for i, e := range q.embedBuilders {
+		c.embedBuilders[i] = e.clone(&c)
+	}
+	c.errs = append([]error(nil), q.errs...)
+	c.headers = make(map[string]string, len(q.headers))
+	for k, v := range q.headers {
+		c.headers[k] = v
+	}
+	return &c
 }
 
-// Select specifies the columns to return
-func (q *QueryBuilder) Select(columns ...string) *QueryBuilder {
-	q.selectQuery = strings.Join(columns, ",")
-	return q
-}
+// Update updates an existing record
+// Struct fields tagged with supabase:"omitzero" are left out of the body when zero,
+// and OmitZero extends this to every field of the struct. Other structs are marshaled
+// by encoding/json unchanged.
+func (q *QueryBuilder) Update(data interface{}) error {
+	q.method = http.MethodPatch
 
-// Where adds a filter condition
-func (q *QueryBuilder) Where(column, operator string, value interface{}) *QueryBuilder {
-	q.filters = append(q.filters, fmt.Sprintf("%s.%s.%v", column, operator, value))
-	return q
+	data, err := q.beforeUpdate(data)
+	if err != nil {
+		return err
+	}
+
+	return q.execute(q.updateBody(data), nil)
 }
 
-// OrWhere adds an OR filter condition
-func (q *QueryBuilder) OrWhere(column, operator string, value interface{}) *QueryBuilder {
-	q.filters = append(q.filters, fmt.Sprintf("or(%s.%s.%v)", column, operator, value))
-	return q
+// UpdateIf applies updates to the rows matching the query only while conditionColumn
+// still satisfies the condition, e.g. UpdateIf("version", "eq", 3, changes) for an
+// optimistic lock, and reports whether any row was updated. The condition is sent as a
+// filter of the same request, so the check and the update are atomic. When updates is
+// a pointer to a struct or map and a row was updated, the row is decoded back into it.
+func (q *QueryBuilder) UpdateIf(conditionColumn, operator string, conditionValue interface{}, updates interface{}) (bool, error) {
+	q.method = http.MethodPatch
+	q.Where(conditionColumn, operator, conditionValue)
+	q.prefer("return=representation")
+
+	data, err := q.beforeUpdate(updates)
+	if err != nil {
+		return false, err
+	}
+
+	var rows []json.RawMessage
+	if err := q.execute(q.updateBody(data), &rows); err != nil {
+		return false, err
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+
+	if v := reflect.ValueOf(updates); v.Kind() == reflect.Ptr && !v.IsNil() {
+		switch v.Elem().Kind() {
+		case reflect.Struct, reflect.Map:
+			return true, q.unmarshal(rows[0], updates)
+		}
+	}
+	return true, nil
 }
 
-// WhereRaw adds a raw filter condition
-func (q *QueryBuilder) WhereRaw(condition string) *QueryBuilder {
-	q.filters = append(q.filters, fmt.Sprintf("and(%s)", condition))
+// AllowWrites permits writes through a builder created with FromView,
+// for views that PostgreSQL can update
+func (q *QueryBuilder) AllowWrites() *QueryBuilder {
+	q.readOnly = false
 	return q
 }
 
-// Order adds an order clause
-func (q *QueryBuilder) Order(column, direction string) *QueryBuilder {
-	q.orderQuery = fmt.Sprintf("order=%s.%s", column, direction)
-	return q
+// updateBody returns the body Update sends for data. Structs are marshaled by
+// encoding/json as given unless zero fields must be left out, with OmitZero or fields
+// tagged supabase:"omitzero"; only then are their fields copied into a map.
+func (q *QueryBuilder) updateBody(data interface{}) interface{} {
+	if !q.omitZero && !hasOmitZeroFields(reflect.TypeOf(data)) {
+		return data
+	}
+	if _, ok := data.(json.Marshaler); ok {
+		return data
+	}
+	return structToMap(data, q.omitZero, q.client.columnNamer)
 }
 
-// Limit sets the maximum number of rows to return
-func (q *QueryBuilder) Limit(limit int) *QueryBuilder {
-	q.limitQuery = fmt.Sprintf("limit=%d", limit)
+// OmitZero omits all zero-valued struct fields from update bodies,
+// so unset fields don't overwrite existing column values
+func (q *QueryBuilder) OmitZero() *QueryBuilder {
+	q.omitZero = true
 	return q
 }
 
-// Offset sets the number of rows to skip
-func (q *QueryBuilder) Offset(offset int) *QueryBuilder {
-	q.offsetQuery = fmt.Sprintf("offset=%d", offset)
-	return q
+// InsertRaw inserts records from pre-encoded JSON, sending the bytes unchanged
+func (q *QueryBuilder) InsertRaw(ctx context.Context, body json.RawMessage) error {
+	q.method = http.MethodPost
+	q.ctx = ctx
+	return q.execute([]byte(body), nil)
 }
 
-// Range sets the range of rows to return
-func (q *QueryBuilder) Range(start, end int) *QueryBuilder {
-	q.rangeQuery = fmt.Sprintf("range=%d-%d", start, end)
-	return q
+// UpdateRaw updates records from pre-encoded JSON, sending the bytes unchanged
+func (q *QueryBuilder) UpdateRaw(ctx context.Context, body json.RawMessage) error {
+	q.method = http.MethodPatch
+	q.ctx = ctx
+	return q.execute([]byte(body), nil)
 }
 
-// Header adds a custom header to the request
-func (q *QueryBuilder) Header(key, value string) *QueryBuilder {
-	q.headers[key] = value
-	return q
+// JSONMergeFunction is the database function UpdateJSON calls, see the README for its definition
+const JSONMergeFunction = "jsonb_merge_update"
+
+// jsonMergeOperators are the filter operators the merge function translates to SQL
+var jsonMergeOperators = map[string]bool{
+	"eq": true, "neq": true, "gt": true, "gte": true, "lt": true, "lte": true,
 }
 
-// Join adds a join clause to the query
-// This uses the PostgREST foreign key join syntax
-func (q *QueryBuilder) Join(foreignTable, localColumn, operator, foreignColumn string) *QueryBuilder {
-	q.joins = append(q.joins, join{
-		foreignTable:  foreignTable,
-		localColumn:   localColumn,
-		operator:      operator,
-		foreignColumn: foreignColumn,
+// UpdateJSON merges patch into the jsonb column of the filtered rows, so
+// UpdateJSON("metadata", map[string]interface{}{"theme": "dark"}) sets one key and keeps
+// the others. PostgREST can't express column || patch in an update, so this calls the
+// JSONMergeFunction RPC with the table, column, patch and filters. Only simple column
+// filters (eq, neq, gt, gte, lt, lte) are supported, and at least one is required.
+// Keys are merged at the top level, like the || operator. The call is sent like the
+// query's other writes, with its context, timeout and headers.
+func (q *QueryBuilder) UpdateJSON(column string, patch map[string]interface{}) error {
+	q.method = http.MethodPatch
+
+	if !identifierPattern.MatchString(column) {
+		return fmt.Errorf("invalid json column %q", column)
+	}
+
+	filters, err := q.jsonFunctionFilters("UpdateJSON")
+	if err != nil {
+		return err
+	}
+
+	return q.callProcedure(JSONMergeFunction, map[string]interface{}{
+		"p_table":   q.table,
+		"p_column":  column,
+		"p_patch":   patch,
+		"p_filters": filters,
 	})
-	return q
 }
 
-// InnerJoin is a convenience method for Join with "eq" operator
-func (q *QueryBuilder) InnerJoin(foreignTable, localColumn, foreignColumn string) *QueryBuilder {
-	return q.Join(foreignTable, localColumn, "eq", foreignColumn)
+// callProcedure sends the query's write to the database function procedure with params,
+// keeping the query's context, timeout, headers and retries, and like other writes
+// invalidates cached reads of the table
+func (q *QueryBuilder) callProcedure(procedure string, params map[string]interface{}) error {
+	// Sent as raw JSON, so the arguments aren't renamed like table columns
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	q.method = http.MethodPost
+	q.procedure = procedure
+	return q.execute(body, nil)
 }
 
-// LeftJoin is a convenience method for left join
-// Note: PostgREST doesn't directly support LEFT JOIN, but we can emulate it
-func (q *QueryBuilder) LeftJoin(foreignTable, localColumn, foreignColumn string) *QueryBuilder {
-	// Add the join
-	q.Join(foreignTable, localColumn, "eq", foreignColumn)
+// jsonFunctionFilters returns the query's filters in the form taken by the jsonb update
+// functions, rejecting filters they can't translate and queries without any
+func (q *QueryBuilder) jsonFunctionFilters(method string) ([]map[string]string, error) {
+	if len(q.orFilters) > 0 || len(q.andFilters) > 0 || len(q.notFilters) > 0 {
+		return nil, fmt.Errorf("%s on %s supports only simple column filters", method, q.table)
+	}
 
-	// Set the Prefer header to include nulls
-	q.Header("Prefer", "missing=null")
+	filters := make([]map[string]string, 0, len(q.filters))
+	for _, f := range q.filters {
+		filterColumn, condition, _ := strings.Cut(f, "=")
+		operator, value, _ := strings.Cut(condition, ".")
+		// The functions quote the column themselves
+		filterColumn = unquoteColumn(filterColumn)
+		if !jsonMergeOperators[operator] || !identifierPattern.MatchString(filterColumn) {
+			return nil, fmt.Errorf("%s on %s doesn't support the filter %q", method, q.table, f)
+		}
+		filters = append(filters, map[string]string{
+			"column":   filterColumn,
+			"operator": operator,
+			"value":    value,
+		})
+	}
 
-	return q
-}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("%s on %s requires at least one filter", method, q.table)
+	}
 
-// Raw sets a raw SQL query to be executed
-// This uses the PostgREST RPC function call mechanism
-func (q *QueryBuilder) Raw(query string) *QueryBuilder {
-	q.rawQuery = query
-	return q
+	if errs := q.Validate(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return filters, nil
 }
 
-// Get executes the query and returns the results
-func (q *QueryBuilder) Get(result interface{}) error {
-	return q.execute(result)
+// JSONPatchFunction is the database function PatchJSON calls, see the README for its definition
+const JSONPatchFunction = "jsonb_patch_update"
+
+// JSONPatchOp is an RFC 6902 operation: Op is add, remove, replace, move, copy or test,
+// Path and From are JSON pointers such as /tags/0, and Value is the value that add,
+// replace and test use
+type JSONPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+	From  string      `json:"from,omitempty"`
 }
 
-// First executes the query and returns the first result
-func (q *QueryBuilder) First(result interface{}) error {
-	q.Limit(1)
-	return q.execute(result)
+// MarshalJSON writes the value of add, replace and test even when it is nil, so
+// setting a key to null isn't mistaken for an operation without a value
+func (op JSONPatchOp) MarshalJSON() ([]byte, error) {
+	type plain JSONPatchOp
+	switch op.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			plain
+			Value interface{} `json:"value"`
+		}{plain(op), op.Value})
+	}
+	return json.Marshal(plain(op))
 }
 
-// Insert inserts a new record
-func (q *QueryBuilder) Insert(data interface{}) error {
-	q.method = http.MethodPost
-	return q.execute(data)
+// validate checks the operation's name and pointers
+func (op JSONPatchOp) validate() error {
+	switch op.Op {
+	case "add", "remove", "replace", "test":
+	case "move", "copy":
+		if !strings.HasPrefix(op.From, "/") {
+			return fmt.Errorf("%s needs a from pointer, got %q", op.Op, op.From)
+		}
+	default:
+		return fmt.Errorf("unknown json patch op %q", op.Op)
+	}
+
+	if op.Path != "" && !strings.HasPrefix(op.Path, "/") {
+		return fmt.Errorf("invalid json pointer %q", op.Path)
+	}
+	return nil
 }
 
-// Update updates an existing record
-func (q *QueryBuilder) Update(data interface{}) error {
+// PatchJSON applies RFC 6902 operations to the jsonb column of the filtered rows, e.g.
+//
+//	q.Where("id", "eq", 7).PatchJSON("doc", []JSONPatchOp{
+//		{Op: "add", Path: "/tags/-", Value: "go"},
+//		{Op: "replace", Path: "/title", Value: "Hello"},
+//	})
+//
+// The operations run in the database through the JSONPatchFunction RPC, so concurrent
+// patches to other parts of a document don't overwrite each other as a read, modify and
+// write would. If an operation fails, e.g. a test op or a missing path, no row changes.
+// Filters are limited like in UpdateJSON.
+func (q *QueryBuilder) PatchJSON(column string, ops []JSONPatchOp) error {
 	q.method = http.MethodPatch
-	return q.execute(data)
+
+	if !identifierPattern.MatchString(column) {
+		return fmt.Errorf("invalid json column %q", column)
+	}
+	if len(ops) == 0 {
+		return fmt.Errorf("PatchJSON on %s: no operations", q.table)
+	}
+	for i, op := range ops {
+		if err := op.validate(); err != nil {
+			return fmt.Errorf("PatchJSON on %s: op %d: %w", q.table, i, err)
+		}
+	}
+
+	filters, err := q.jsonFunctionFilters("PatchJSON")
+	if err != nil {
+		return err
+	}
+
+	return q.callProcedure(JSONPatchFunction, map[string]interface{}{
+		"p_table":   q.table,
+		"p_column":  column,
+		"p_ops":     ops,
+		"p_filters": filters,
+	})
 }
 
 // Delete deletes records
 func (q *QueryBuilder) Delete() error {
 	q.method = http.MethodDelete
-	return q.execute(nil)
+	return q.execute(nil, nil)
+}
+
+// DeleteReturning deletes records and decodes the removed rows into result
+func (q *QueryBuilder) DeleteReturning(result interface{}) error {
+	q.method = http.MethodDelete
+	q.prefer("return=representation")
+	return q.execute(nil, result)
+}
+
+// CountMode selects how PostgREST counts the rows matching a query
+type CountMode string
+
+const (
+	// CountExact runs a full count, which is slow on large tables
+	CountExact CountMode = "exact"
+	// CountPlanned uses the query planner's row estimate
+	CountPlanned CountMode = "planned"
+	// CountEstimated counts exactly up to the max-rows setting and uses the planner estimate above it
+	CountEstimated CountMode = "estimated"
+)
+
+// CountMode requests the total row count with the given mode, sent as Prefer: count=<mode>.
+// The total is returned in the Content-Range header.
+func (q *QueryBuilder) CountMode(mode CountMode) *QueryBuilder {
+	q.countQuery = "count=" + string(mode)
+	return q
 }
 
 // Count sets the query to return an exact count
 func (q *QueryBuilder) Count() *QueryBuilder {
-	q.countQuery = "count=exact"
-	return q
+	return q.CountMode(CountExact)
+}
+
+// CountEstimated requests an estimated count, cheaper than Count on huge tables
+func (q *QueryBuilder) CountEstimated() *QueryBuilder {
+	return q.CountMode(CountEstimated)
+}
+
+// CountPlanned requests the planner's row estimate, the cheapest count mode
+func (q *QueryBuilder) CountPlanned() *QueryBuilder {
+	return q.CountMode(CountPlanned)
 }
 
-// execute builds and executes the request
-func (q *QueryBuilder) execute(data interface{}) error {
+// execute builds and executes the request, sending body for writes
+// and decoding the response into result when it is non-nil
+func (q *QueryBuilder) execute(body interface{}, result interface{}) error {
+	resp, err := q.send(body)
+	if err != nil {
+		return err
+	}
+
+	return q.decode(resp, result)
+}
+
+// send builds and sends the request, returning the response when its status is not an error
+func (q *QueryBuilder) send(body interface{}) (*resty.Response, error) {
+	if errs := q.Validate(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if q.tx != nil && q.method != http.MethodGet && q.method != http.MethodHead {
+		if err := q.tx.checkWrite(q); err != nil {
+			return nil, err
+		}
+	}
+
 	var endpoint string
 
 	// If it's a raw query, use the RPC endpoint
@@ -230,118 +1685,223 @@ func (q *QueryBuilder) execute(data interface{}) error {
 			Query string `json:"query"`
 		}
 
-		data = sqlRequest{
+		body = sqlRequest{
 			Query: q.rawQuery,
 		}
 	} else {
 		// For normal queries, use the table endpoint
 		endpoint = fmt.Sprintf("%s/rest/v1/%s", q.client.GetBaseURL(), q.table)
+		if q.function != "" {
+			endpoint = fmt.Sprintf("%s/rest/v1/rpc/%s", q.client.GetBaseURL(), q.function)
+		}
+		if q.procedure != "" {
+			endpoint = fmt.Sprintf("%s/rest/v1/rpc/%s", q.client.GetBaseURL(), q.procedure)
+		}
+
+		// Write []byte fields as bytea hex and rename untagged fields; raw bodies are sent untouched
+		if _, raw := body.([]byte); body != nil && !raw && (q.client.byteaEncoding == ByteaHex || q.client.columnNamer != nil) {
+			encoded, err := encodeBody(body, q.client.byteaEncoding, q.client.columnNamer)
+			if err != nil {
+				return nil, err
+			}
+			body = encoded
+		}
 	}
 
+	ctx, cancel := q.requestContext()
+	defer cancel()
+
 	req := q.client.RawRequest()
+	if q.retryWrites {
+		req.SetContext(retryContext(ctx))
+	} else if ctx != nil {
+		req.SetContext(ctx)
+	}
+
+	// Target the client's schema: Accept-Profile for reads, Content-Profile for writes
+	if schema := q.client.schema; schema != "" {
+		if q.method == http.MethodGet || q.method == http.MethodHead {
+			req.SetHeader("Accept-Profile", schema)
+		} else {
+			req.SetHeader("Content-Profile", schema)
+		}
+	}
 
 	// Add custom headers
 	for k, v := range q.headers {
 		req.SetHeader(k, v)
 	}
 
-	// If it's not a raw query, build the query parameters
-	if q.rawQuery == "" {
+	// Combine preferences set on the builder with the count mode and the
+	// timeout that lets the database abort the statement with the context
+	var preferences []string
+	if current := q.headers["Prefer"]; current != "" {
+		preferences = append(preferences, current)
+	}
+	if q.countQuery != "" {
+		preferences = append(preferences, q.countQuery)
+	}
+	if timeout := timeoutPreference(ctx); timeout != "" {
+		preferences = append(preferences, timeout)
+	}
+	if len(preferences) > 0 {
+		req.SetHeader("Prefer", strings.Join(preferences, ", "))
+	}
+
+	// If it's not a raw query, build the query parameters; a procedure takes them as arguments
+	if q.rawQuery == "" && q.procedure == "" {
 		// Build query parameters
 		queryParams := url.Values{}
 
-		// Add select fields
-		if q.selectQuery != "" {
-			queryParams.Set("select", q.selectQuery)
+		// Add the arguments of the function the rows are read from
+		for _, arg := range q.functionArgs {
+			queryParams.Set(arg.key, arg.value)
 		}
 
-		// Add joins
-		if len(q.joins) > 0 {
-			// For each join, we need to modify the select parameter
-			// to include the joined table columns
-			var joinSelects []string
+		// Add select fields, joins and embeds
+		if selectQuery := q.buildSelect(); selectQuery != "" {
+			queryParams.Set("select", selectQuery)
+		}
 
-			for _, j := range q.joins {
-				// Format: foreignTable(*)
-				joinSelects = append(joinSelects, fmt.Sprintf("%s(*)", j.foreignTable))
-			}
+		// Add filters, each stored as column=operator.value
+		for _, f := range q.allFilters() {
+			key, value, _ := strings.Cut(f, "=")
+			queryParams.Add(key, value)
+		}
 
-			// If we already have select fields, append the joins
-			if q.selectQuery != "" {
-				queryParams.Set("select", fmt.Sprintf("%s,%s",
-					q.selectQuery,
-					strings.Join(joinSelects, ",")))
-			} else {
-				// Otherwise, select all columns from the main table and the joined tables
-				queryParams.Set("select", fmt.Sprintf("*,%s", strings.Join(joinSelects, ",")))
-			}
+		// Add filters, order and limits scoped to embedded resources
+		for _, p := range q.embedParams() {
+			key, value, _ := strings.Cut(p, "=")
+			queryParams.Add(key, value)
 		}
 
-		// Add filters
-		for _, f := range q.filters {
-			queryParams.Add("and", f)
+		// Add the columns of the unique constraint used to detect duplicates
+		if q.onConflict != "" {
+			queryParams.Set("on_conflict", q.onConflict)
 		}
 
-		// Add order
-		if q.orderQuery != "" {
-			queryParams.Set("order", q.orderQuery)
+		// Add order, stored as order=column.direction, or the client's default order for reads
+		if order := q.effectiveOrder(); order != "" {
+			queryParams.Set("order", order)
 		}
 
-		// Add limit and offset
-		if q.limitQuery != "" {
-			queryParams.Set("limit", q.limitQuery)
+		// Add limit and offset, capped by the client's maximum limit on reads
+		if limit := q.effectiveLimit(); limit != "" {
+			queryParams.Set("limit", limit)
 		}
 
 		if q.offsetQuery != "" {
-			queryParams.Set("offset", q.offsetQuery)
+			queryParams.Set("offset", strings.TrimPrefix(q.offsetQuery, "offset="))
+		}
+
+		// Add raw parameters last, keeping those set by the builder
+		for _, p := range q.rawParams {
+			queryParams.Add(p.key, p.value)
 		}
 
-		// Add range header if specified
+		// Add range headers if specified
 		if q.rangeQuery != "" {
-			req.SetHeader("Range", q.rangeQuery)
+			rangeValue, unit := q.rangeHeaders()
+			req.SetHeader("Range", rangeValue)
+			req.SetHeader("Range-Unit", unit)
 		}
 
 		// Set query parameters
 		req.SetQueryParamsFromValues(queryParams)
 	}
 
-	var resp *resty.Response
-	var err error
+	// Serve identical reads from the client's cache
+	cacheKey := q.cacheKey(endpoint, req)
+	if cacheKey != "" {
+		if resp, ok := q.client.queryCache.get(cacheKey); ok {
+			return resp, nil
+		}
+	}
 
 	switch q.method {
-	case http.MethodGet:
-		resp, err = req.Get(endpoint)
-	case http.MethodPost:
-		resp, err = req.SetBody(data).Post(endpoint)
-	case http.MethodPatch:
-		resp, err = req.SetBody(data).Patch(endpoint)
-	case http.MethodDelete:
-		resp, err = req.Delete(endpoint)
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+	case http.MethodPost, http.MethodPatch:
+		req.SetBody(body)
 	default:
-		return fmt.Errorf("unsupported HTTP method: %s", q.method)
+		return nil, fmt.Errorf("unsupported HTTP method: %s", q.method)
+	}
+
+	// Fail fast while the backend is down
+	var probe bool
+	if q.client.breaker != nil {
+		var err error
+		if probe, err = q.client.breaker.allow(); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", q.method, q.table, err)
+		}
+	}
+
+	resp, err := req.Execute(q.method, endpoint)
+	if q.client.breaker != nil {
+		q.client.breaker.record(probe, resp, err)
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("API error: %s", resp.String())
+		return nil, q.functionError(newAPIError(resp))
+	}
+
+	if q.writeResult != nil {
+		q.writeResult.StatusCode = resp.StatusCode()
+		q.writeResult.Rows = append(json.RawMessage(nil), resp.Body()...)
+	}
+
+	if cache := q.client.queryCache; cache != nil {
+		if cacheKey != "" {
+			cache.put(cacheKey, q.table, resp, q.cacheTTL)
+		} else if q.method != http.MethodGet && q.method != http.MethodHead {
+			// Cached reads of the table may no longer match its rows
+			cache.invalidate(q.table)
+		}
+	}
+
+	return resp, nil
+}
+
+// decode unmarshals the rows of a successful response into result when it is non-nil
+func (q *QueryBuilder) decode(resp *resty.Response, result interface{}) error {
+	if resp.StatusCode() == http.StatusNotModified {
+		return ErrNotModified
+	}
+
+	// Writes with return=minimal answer 204 No Content, leaving nothing to decode
+	if resp.StatusCode() == http.StatusNoContent || len(resp.Body()) == 0 {
+		return nil
 	}
 
-	// For methods that return data, unmarshal the response
-	if q.method == http.MethodGet && data != nil {
-		return json.Unmarshal(resp.Body(), data)
+	if result == nil {
+		return nil
 	}
 
-	// For insert operations, update the ID of the inserted record
-	if q.method == http.MethodPost && data != nil {
-		return json.Unmarshal(resp.Body(), data)
+	// Unmarshal the returned rows for reads and representations of writes
+	if err := q.unmarshal(resp.Body(), result); err != nil {
+		return err
+	}
+
+	// Rows that were read are post-processed by their AfterFind hooks
+	if q.method == http.MethodGet || q.method == http.MethodHead {
+		return q.afterFind(result)
 	}
 
 	return nil
 }
 
+// unmarshal decodes returned rows into result, applying the client's ByteaEncoding
+// and ColumnNamer
+func (q *QueryBuilder) unmarshal(raw []byte, result interface{}) error {
+	if q.client.byteaEncoding == ByteaHex || q.client.columnNamer != nil {
+		return decodeBody(raw, result, q.client.byteaEncoding, q.client.columnNamer)
+	}
+	return json.Unmarshal(raw, result)
+}
+
 // Single sets the query to return a single result
 func (q *QueryBuilder) Single() *QueryBuilder {
 	q.singleResult = true
@@ -356,15 +1916,24 @@ func (q *QueryBuilder) Filter(column, operator string, value interface{}) *Query
 // BuildURL builds the URL for the query
 func (q *QueryBuilder) BuildURL() string {
 	// Simple implementation for tests
-	url := "/" + q.table
+	path := "/" + q.table
+	if q.function != "" {
+		path = "/rpc/" + q.function
+	}
 
 	params := []string{}
-	if q.selectQuery != "" {
-		params = append(params, q.selectQuery)
+	for _, arg := range q.functionArgs {
+		params = append(params, arg.key+"="+arg.value)
+	}
+	if selectQuery := q.buildSelect(); selectQuery != "" {
+		params = append(params, "select="+selectQuery)
 	}
 
-	for _, filter := range q.filters {
-		params = append(params, filter)
+	params = append(params, q.allFilters()...)
+	params = append(params, q.embedParams()...)
+
+	if q.onConflict != "" {
+		params = append(params, "on_conflict="+q.onConflict)
 	}
 
 	if q.orderQuery != "" {
@@ -383,17 +1952,42 @@ func (q *QueryBuilder) BuildURL() string {
 		params = append(params, q.countQuery)
 	}
 
+	for _, p := range q.rawParams {
+		params = append(params, url.QueryEscape(p.key)+"="+url.QueryEscape(p.value))
+	}
+
 	if len(params) > 0 {
-		url += "?" + strings.Join(params, "&")
+		path += "?" + strings.Join(params, "&")
 	}
 
-	return url
+	return path
 }
 
-// Execute executes the query and returns the results
+// Execute executes the query and returns the results, like Get
 func (q *QueryBuilder) Execute(result interface{}) error {
-	// Implementation for tests
-	return nil
+	return q.Get(result)
+}
+
+// allFilters returns every filter in the order they are sent
+func (q *QueryBuilder) allFilters() []string {
+	var filters []string
+	filters = append(filters, q.filters...)
+	filters = append(filters, q.orFilters...)
+	filters = append(filters, q.andFilters...)
+	filters = append(filters, q.notFilters...)
+	return filters
+}
+
+// embedParams returns the query parameters of every embed builder
+func (q *QueryBuilder) embedParams() []string {
+	var params []string
+	for _, e := range q.embedBuilders {
+		params = append(params, e.params()...)
+	}
+	for _, o := range q.foreignOrders {
+		params = append(params, o.key+".order="+o.value)
+	}
+	return params
 }
 
 // Or adds OR filters
@@ -412,13 +2006,30 @@ func (q *QueryBuilder) And(filters ...string) *QueryBuilder {
 	return q
 }
 
-// Not adds a NOT filter
+// Not adds a negated filter in PostgREST form, e.g. status=not.eq.inactive,
+// role=not.in.(guest,banned) or deleted_at=not.is.null
 func (q *QueryBuilder) Not(column, operator string, value interface{}) *QueryBuilder {
-	filter := fmt.Sprintf("not.%s=%s.%v", column, operator, value)
+	filter := fmt.Sprintf("%s=not.%s.%s", quoteColumn(column), operator, formatOperand(operator, value))
 	q.notFilters = append(q.notFilters, filter)
 	return q
 }
 
+// NotOr adds a negated OR group, matching rows where none of the filters hold
+func (q *QueryBuilder) NotOr(filters ...string) *QueryBuilder {
+	if len(filters) > 0 {
+		q.notFilters = append(q.notFilters, "not.or=("+strings.Join(filters, ",")+")")
+	}
+	return q
+}
+
+// NotAnd adds a negated AND group, matching rows where not all of the filters hold
+func (q *QueryBuilder) NotAnd(filters ...string) *QueryBuilder {
+	if len(filters) > 0 {
+		q.notFilters = append(q.notFilters, "not.and=("+strings.Join(filters, ",")+")")
+	}
+	return q
+}
+
 // ForeignTable creates a query builder for a foreign table
 func (q *QueryBuilder) ForeignTable(foreignTable string) *QueryBuilder {
 	return NewQueryBuilder(q.table + "." + foreignTable)
@@ -1,10 +1,19 @@
 package supabaseorm
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type TestUser struct {
@@ -24,17 +33,17 @@ func TestSelect(t *testing.T) {
 		{
 			name:     "select all columns",
 			columns:  []string{"*"},
-			expected: "select=*",
+			expected: "*",
 		},
 		{
 			name:     "select specific columns",
 			columns:  []string{"id", "name", "email"},
-			expected: "select=id,name,email",
+			expected: "id,name,email",
 		},
 		{
 			name:     "select with nested columns",
 			columns:  []string{"id", "profile(avatar_url)"},
-			expected: "select=id,profile(avatar_url)",
+			expected: "id,profile(avatar_url)",
 		},
 	}
 
@@ -278,7 +287,11 @@ func TestCount(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			qb := NewQueryBuilder("users")
-			qb.Count()
+			if tt.exact {
+				qb.Count()
+			} else {
+				qb.CountEstimated()
+			}
 
 			if qb.countQuery != tt.expected {
 				t.Errorf("Count() = %v, want %v", qb.countQuery, tt.expected)
@@ -345,7 +358,7 @@ func TestBuildURL(t *testing.T) {
 func TestExecute(t *testing.T) {
 	// Mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/users" && r.URL.RawQuery == "select=id,name&age=gt.18" {
+		if query := r.URL.Query(); r.URL.Path == "/rest/v1/users" && query.Get("select") == "id,name" && query.Get("age") == "gt.18" {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`[{"id":1,"name":"John"},{"id":2,"name":"Jane"}]`))
@@ -384,7 +397,7 @@ func TestExecute(t *testing.T) {
 func TestInsert(t *testing.T) {
 	// Mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/users" && r.Method == "POST" {
+		if r.URL.Path == "/rest/v1/users" && r.Method == "POST" {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusCreated)
 			w.Write([]byte(`{"id":3,"name":"Alice","email":"alice@example.com","age":25}`))
@@ -418,7 +431,7 @@ func TestInsert(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	// Mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/users" && r.Method == "PATCH" && r.URL.RawQuery == "id=eq.1" {
+		if r.URL.Path == "/rest/v1/users" && r.Method == "PATCH" && r.URL.RawQuery == "id=eq.1" {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"id":1,"name":"John Updated","email":"john@example.com","age":30}`))
@@ -452,7 +465,7 @@ func TestUpdate(t *testing.T) {
 func TestDelete(t *testing.T) {
 	// Mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/users" && r.Method == "DELETE" && r.URL.RawQuery == "id=eq.2" {
+		if r.URL.Path == "/rest/v1/users" && r.Method == "DELETE" && r.URL.RawQuery == "id=eq.2" {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"id":2,"name":"Jane","email":"jane@example.com","age":28}`))
@@ -565,14 +578,28 @@ func TestNot(t *testing.T) {
 			column:   "status",
 			operator: "eq",
 			value:    "inactive",
-			expected: "not.status=eq.inactive",
+			expected: "status=not.eq.inactive",
 		},
 		{
 			name:     "not in",
 			column:   "role",
 			operator: "in",
 			value:    []string{"guest", "banned"},
-			expected: "not.role=in.(guest,banned)",
+			expected: "role=not.in.(guest,banned)",
+		},
+		{
+			name:     "not like",
+			column:   "email",
+			operator: "like",
+			value:    "*@spam.com",
+			expected: "email=not.like.*@spam.com",
+		},
+		{
+			name:     "not is null",
+			column:   "deleted_at",
+			operator: "is",
+			value:    "null",
+			expected: "deleted_at=not.is.null",
 		},
 	}
 
@@ -600,7 +627,7 @@ func TestForeignTable(t *testing.T) {
 func TestRPC(t *testing.T) {
 	// Mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/rpc/get_user_by_id" && r.Method == "POST" {
+		if r.URL.Path == "/rest/v1/rpc/get_user_by_id" && r.Method == "POST" {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"id":1,"name":"John","email":"john@example.com","age":30}`))
@@ -637,3 +664,2270 @@ func TestRPC(t *testing.T) {
 		t.Errorf("RPC() = %v, want %v", user, expected)
 	}
 }
+
+func TestSelectCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*QueryBuilder)
+		expected string
+	}{
+		{
+			name: "count only",
+			setup: func(qb *QueryBuilder) {
+				qb.SelectCount("posts")
+			},
+			expected: "/users?select=*,posts(count)",
+		},
+		{
+			name: "count with columns and join",
+			setup: func(qb *QueryBuilder) {
+				qb.Select("id", "name")
+				qb.InnerJoin("profiles", "id", "user_id")
+				qb.SelectCount("posts")
+			},
+			expected: "/users?select=id,name,profiles(*),posts(count)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewQueryBuilder("users")
+			tt.setup(qb)
+
+			url := qb.BuildURL()
+			if url != tt.expected {
+				t.Errorf("BuildURL() = %v, want %v", url, tt.expected)
+			}
+		})
+	}
+
+	var user struct {
+		ID        int           `json:"id"`
+		PostCount EmbeddedCount `json:"posts"`
+	}
+	if err := json.Unmarshal([]byte(`{"id":1,"posts":[{"count":3}]}`), &user); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if user.PostCount != 3 {
+		t.Errorf("PostCount = %v, want %v", user.PostCount, 3)
+	}
+}
+
+func TestUpdateOmitZero(t *testing.T) {
+	type profileUpdate struct {
+		Name  string `json:"name"`
+		Age   int    `json:"age"`
+		Email string `json:"email" supabase:"omitzero"`
+		// Shared with WhereStruct, which reads the column and operator
+		Role string `json:"role" supabase:"role,eq,omitzero"`
+	}
+
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			json.NewDecoder(r.Body).Decode(&body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	t.Run("tagged field omitted by default", func(t *testing.T) {
+		body = nil
+		err := client.Table("users").Where("id", "eq", 1).Update(profileUpdate{Name: "John"})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		expected := map[string]interface{}{"name": "John", "age": float64(0)}
+		if !reflect.DeepEqual(body, expected) {
+			t.Errorf("Update() body = %v, want %v", body, expected)
+		}
+	})
+
+	t.Run("all zero fields omitted with option", func(t *testing.T) {
+		body = nil
+		err := client.Table("users").Where("id", "eq", 1).OmitZero().Update(&profileUpdate{Name: "John"})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		expected := map[string]interface{}{"name": "John"}
+		if !reflect.DeepEqual(body, expected) {
+			t.Errorf("Update() body = %v, want %v", body, expected)
+		}
+	})
+}
+
+// upperName marshals itself, so Update must not copy its fields
+type upperName struct {
+	Name string
+}
+
+func (n upperName) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"name": strings.ToUpper(n.Name)})
+}
+
+func TestUpdateMarshalsStructs(t *testing.T) {
+	type Audit struct {
+		UpdatedBy string `json:"updated_by"`
+	}
+	type accountUpdate struct {
+		*Audit
+		ID      int64  `json:"id,string"`
+		Balance int    `json:"balance,omitempty"`
+		Note    string `json:"note"`
+	}
+
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	// Without zero-value options the body is exactly what encoding/json produces
+	updates := []interface{}{
+		accountUpdate{Audit: &Audit{UpdatedBy: "ann"}, ID: 42, Note: ""},
+		accountUpdate{ID: 7, Balance: 10},
+		upperName{Name: "john"},
+	}
+	for _, update := range updates {
+		if err := client.Table("accounts").Where("id", "eq", 1).Update(update); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		want, _ := json.Marshal(update)
+		if string(body) != string(want) {
+			t.Errorf("Update(%T) body = %s, want %s", update, body, want)
+		}
+	}
+
+	// OmitZero keeps the string option and embedded pointer fields
+	err := client.Table("accounts").Where("id", "eq", 1).OmitZero().Update(accountUpdate{Audit: &Audit{UpdatedBy: "ann"}, ID: 42})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(body, &got)
+	if want := map[string]interface{}{"updated_by": "ann", "id": "42"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() with OmitZero body = %v, want %v", got, want)
+	}
+}
+
+func TestInsertRawUpdateRaw(t *testing.T) {
+	raw := json.RawMessage(`{"name": "Alice",  "tags": ["a","b"]}`)
+
+	var method string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	if err := client.Table("users").InsertRaw(context.Background(), raw); err != nil {
+		t.Fatalf("InsertRaw() error = %v", err)
+	}
+	if method != http.MethodPost || string(body) != string(raw) {
+		t.Errorf("InsertRaw() sent %s %s, want POST %s", method, body, raw)
+	}
+
+	if err := client.Table("users").Where("id", "eq", 1).UpdateRaw(context.Background(), raw); err != nil {
+		t.Fatalf("UpdateRaw() error = %v", err)
+	}
+	if method != http.MethodPatch || string(body) != string(raw) {
+		t.Errorf("UpdateRaw() sent %s %s, want PATCH %s", method, body, raw)
+	}
+}
+
+func TestWhereJSONArrayLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		column   string
+		operator string
+		n        int
+		expected []string
+	}{
+		{
+			name:     "at least",
+			column:   "tags",
+			operator: "gte",
+			n:        3,
+			expected: []string{"tags=cs.[]", "tags->2=not.is.null"},
+		},
+		{
+			name:     "more than",
+			column:   "tags",
+			operator: "gt",
+			n:        3,
+			expected: []string{"tags=cs.[]", "tags->3=not.is.null"},
+		},
+		{
+			name:     "fewer than",
+			column:   "tags",
+			operator: "lt",
+			n:        2,
+			expected: []string{"tags=cs.[]", "tags->1=is.null"},
+		},
+		{
+			name:     "exactly",
+			column:   "tags",
+			operator: "eq",
+			n:        2,
+			expected: []string{"tags=cs.[]", "tags->1=not.is.null", "tags->2=is.null"},
+		},
+		{
+			name:     "not exactly",
+			column:   "tags",
+			operator: "neq",
+			n:        2,
+			expected: []string{"tags=cs.[]", "or=(tags->1.is.null,tags->2.not.is.null)"},
+		},
+		{
+			name:     "not empty",
+			column:   "tags",
+			operator: "neq",
+			n:        0,
+			expected: []string{"tags=cs.[]", "tags->0=not.is.null"},
+		},
+		{
+			name:     "path with cast",
+			column:   "metadata->labels::text",
+			operator: "gte",
+			n:        2,
+			expected: []string{"metadata->labels=cs.[]", "metadata->labels->1::text=not.is.null"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewQueryBuilder("posts")
+			qb.WhereJSONArrayLength(tt.column, tt.operator, tt.n)
+
+			if !reflect.DeepEqual(qb.filters, tt.expected) {
+				t.Errorf("WhereJSONArrayLength() = %v, want %v", qb.filters, tt.expected)
+			}
+			if errs := qb.Validate(); len(errs) != 0 {
+				t.Errorf("Validate() = %v", errs)
+			}
+		})
+	}
+
+	invalid := []struct {
+		column, operator string
+	}{
+		{"tags", "like"},
+		{"metadata->>labels", "gte"},
+		{"tags::text;drop", "gte"},
+	}
+	for _, tt := range invalid {
+		qb := NewQueryBuilder("posts")
+		qb.WhereJSONArrayLength(tt.column, tt.operator, 2)
+		if len(qb.errs) != 1 || len(qb.filters) != 0 {
+			t.Errorf("WhereJSONArrayLength(%q, %q) recorded %d errors and %v, want 1 error and no filters", tt.column, tt.operator, len(qb.errs), qb.filters)
+		}
+	}
+}
+
+func TestRPCSchema(t *testing.T) {
+	var path, acceptProfile, contentProfile string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		acceptProfile = r.Header.Get("Accept-Profile")
+		contentProfile = r.Header.Get("Content-Profile")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total":42}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var out struct {
+		Total int `json:"total"`
+	}
+	err := client.Schema("analytics").RPC("rollup", map[string]interface{}{"day": "2024-01-01"}, &out)
+	if err != nil {
+		t.Fatalf("RPC() error = %v", err)
+	}
+
+	if path != "/rest/v1/rpc/rollup" {
+		t.Errorf("RPC() path = %v, want %v", path, "/rest/v1/rpc/rollup")
+	}
+	if acceptProfile != "analytics" || contentProfile != "analytics" {
+		t.Errorf("RPC() profiles = %q/%q, want analytics", acceptProfile, contentProfile)
+	}
+	if out.Total != 42 {
+		t.Errorf("RPC() result = %v, want %v", out.Total, 42)
+	}
+
+	if err := client.RPC("rollup", nil, nil); err != nil {
+		t.Fatalf("RPC() error = %v", err)
+	}
+	if acceptProfile != "" {
+		t.Errorf("RPC() without schema sent Accept-Profile %q", acceptProfile)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	qb := NewQueryBuilder("users")
+	qb.Select("id", "name").Where("age", "gt", 18).Order("created_at", "desc")
+	if errs := qb.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+
+	qb = NewQueryBuilder("users")
+	qb.Where("age", "bigger", 18).Order("created_at", "sideways")
+	if errs := qb.Validate(); len(errs) != 2 {
+		t.Errorf("Validate() = %v, want 2 errors", errs)
+	}
+
+	// Deletes without filters are rejected before anything is sent
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+	qb = client.Table("users").Order("id", "up")
+	qb.method = http.MethodDelete
+	if errs := qb.Validate(); len(errs) != 2 {
+		t.Errorf("Validate() = %v, want 2 errors", errs)
+	}
+
+	if err := client.Table("users").Delete(); err == nil {
+		t.Error("Delete() without filters should fail validation")
+	}
+
+	// A bad operator leaves the delete unfiltered; both problems are reported, the
+	// filter's first
+	qb = client.Table("users").Where("age", "bigger", 18)
+	qb.method = http.MethodDelete
+	errs := qb.Validate()
+	if len(errs) != 2 ||
+		!strings.Contains(errs[0].Error(), `unknown operator "bigger"`) ||
+		!strings.Contains(errs[1].Error(), "requires at least one filter") {
+		t.Errorf("Validate() = %v, want the operator error then the missing filter", errs)
+	}
+
+	err := client.Table("users").Where("age", "bigger", 18).Delete()
+	if err == nil || !strings.Contains(err.Error(), "bigger") || !strings.Contains(err.Error(), "requires at least one filter") {
+		t.Errorf("Delete() error = %v, want both validation errors", err)
+	}
+	if requests != 0 {
+		t.Errorf("Validate() sent %d requests, want 0", requests)
+	}
+}
+
+func TestRangeUnit(t *testing.T) {
+	var rangeHeader, unitHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader = r.Header.Get("Range")
+		unitHeader = r.Header.Get("Range-Unit")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	tests := []struct {
+		name          string
+		setup         func(*QueryBuilder)
+		expectedRange string
+		expectedUnit  string
+	}{
+		{
+			name: "items by default",
+			setup: func(qb *QueryBuilder) {
+				qb.Range(0, 9)
+			},
+			expectedRange: "0-9",
+			expectedUnit:  "items",
+		},
+		{
+			name: "explicit bytes",
+			setup: func(qb *QueryBuilder) {
+				qb.Range(0, 1023).RangeUnit(RangeUnitBytes)
+			},
+			expectedRange: "bytes=0-1023",
+			expectedUnit:  "bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := client.Table("users")
+			tt.setup(qb)
+
+			var users []TestUser
+			if err := qb.Get(&users); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if rangeHeader != tt.expectedRange || unitHeader != tt.expectedUnit {
+				t.Errorf("headers = %q/%q, want %q/%q", rangeHeader, unitHeader, tt.expectedRange, tt.expectedUnit)
+			}
+		})
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	const etag = `"abc123"`
+
+	var ifMatch string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifMatch = r.Header.Get("If-Match")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(`[{"id":1,"name":"John"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var users []TestUser
+	if err := client.Table("users").IfMatch(etag).Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if ifMatch != etag {
+		t.Errorf("If-Match = %q, want %q", ifMatch, etag)
+	}
+
+	err := client.Table("users").IfNoneMatch(etag).Get(&users)
+	if !errors.Is(err, ErrNotModified) {
+		t.Errorf("Get() error = %v, want ErrNotModified", err)
+	}
+}
+
+func TestDeleteReturning(t *testing.T) {
+	var prefer, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		prefer = r.Header.Get("Prefer")
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":2,"name":"Jane"},{"id":3,"name":"Bob"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var deleted []TestUser
+	err := client.Table("users").Where("id", "in", []int{2, 3}).DeleteReturning(&deleted)
+	if err != nil {
+		t.Fatalf("DeleteReturning() error = %v", err)
+	}
+
+	if prefer != "return=representation" {
+		t.Errorf("Prefer = %q, want %q", prefer, "return=representation")
+	}
+	if query != "id=in.%282%2C3%29" {
+		t.Errorf("query = %q, want %q", query, "id=in.%282%2C3%29")
+	}
+
+	expected := []TestUser{{ID: 2, Name: "Jane"}, {ID: 3, Name: "Bob"}}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("DeleteReturning() = %v, want %v", deleted, expected)
+	}
+}
+
+func TestFilterTemplate(t *testing.T) {
+	byStatus := NewFilterTemplate("status", "eq")
+	byIDs := NewFilterTemplate("id", "in")
+
+	tests := []struct {
+		name     string
+		template *FilterTemplate
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "first value",
+			template: byStatus,
+			value:    "active",
+			expected: "status=eq.active",
+		},
+		{
+			name:     "second value",
+			template: byStatus,
+			value:    "banned",
+			expected: "status=eq.banned",
+		},
+		{
+			name:     "list value",
+			template: byIDs,
+			value:    []int{1, 2},
+			expected: "id=in.(1,2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewQueryBuilder("users")
+			qb.WhereTemplate(tt.template, tt.value)
+
+			if len(qb.filters) != 1 || qb.filters[0] != tt.expected {
+				t.Errorf("WhereTemplate() = %v, want %v", qb.filters, []string{tt.expected})
+			}
+		})
+	}
+
+	if err := byStatus.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+
+	// Templates are validated when built
+	bad := NewFilterTemplate("status", "bigger")
+	if err := bad.Err(); err == nil || !strings.Contains(err.Error(), `unknown operator "bigger"`) {
+		t.Errorf("Err() = %v, want the unknown operator", err)
+	}
+	qb := NewQueryBuilder("users").WhereTemplate(bad, "active")
+	if errs := qb.Validate(); len(errs) != 1 || len(qb.filters) != 0 {
+		t.Errorf("Validate() = %v with filters %v, want one error and no filter", errs, qb.filters)
+	}
+}
+
+func BenchmarkFilterTemplate(b *testing.B) {
+	byStatus := NewFilterTemplate("status", "eq")
+
+	b.Run("template", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewQueryBuilder("users").WhereTemplate(byStatus, "active")
+		}
+	})
+
+	b.Run("where", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewQueryBuilder("users").Where("status", "eq", "active")
+		}
+	})
+}
+
+func TestSelectSpread(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*QueryBuilder)
+		expected string
+	}{
+		{
+			name: "spread count",
+			setup: func(qb *QueryBuilder) {
+				qb.Select("id").SelectSpread("posts", "count")
+			},
+			expected: "/users?select=id,...posts(count)",
+		},
+		{
+			name: "spread with alias next to embedded count",
+			setup: func(qb *QueryBuilder) {
+				qb.SelectCount("comments").SelectSpread("posts", "post_count:count()")
+			},
+			expected: "/users?select=*,comments(count),...posts(post_count:count())",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewQueryBuilder("users")
+			tt.setup(qb)
+
+			url := qb.BuildURL()
+			if url != tt.expected {
+				t.Errorf("BuildURL() = %v, want %v", url, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBatchInsert(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 2 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"code":"23505","message":"duplicate key value violates unique constraint"}`))
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	users := []TestUser{
+		{ID: 1, Name: "A"}, {ID: 2, Name: "B"},
+		{ID: 3, Name: "C"}, {ID: 4, Name: "D"},
+		{ID: 5, Name: "E"},
+	}
+
+	result, err := client.Table("users").BatchInsert(users, 2)
+	if err == nil {
+		t.Fatal("BatchInsert() should fail on the second chunk")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "23505" {
+		t.Errorf("BatchInsert() error = %v, want APIError 23505", err)
+	}
+	if result.Inserted != 2 || result.FailedChunk != 1 || len(result.Rows) != 2 {
+		t.Errorf("BatchInsert() = %+v, want 2 inserted, failed chunk 1, 2 rows", result)
+	}
+	if requests != 2 {
+		t.Errorf("BatchInsert() sent %d requests, want 2", requests)
+	}
+
+	var first TestUser
+	if err := json.Unmarshal(result.Rows[0], &first); err != nil || first.Name != "A" {
+		t.Errorf("Rows[0] = %s, want user A", result.Rows[0])
+	}
+}
+
+func TestNotGroups(t *testing.T) {
+	qb := NewQueryBuilder("users")
+	qb.NotOr("age.lt.18", "status.eq.banned").NotAnd("role.eq.admin", "active.is.false")
+
+	expected := []string{
+		"not.or=(age.lt.18,status.eq.banned)",
+		"not.and=(role.eq.admin,active.is.false)",
+	}
+	if !reflect.DeepEqual(qb.notFilters, expected) {
+		t.Errorf("NotOr()/NotAnd() = %v, want %v", qb.notFilters, expected)
+	}
+
+	if errs := qb.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+
+	qb = NewQueryBuilder("users")
+	qb.Not("status", "eq", "inactive")
+	if url := qb.BuildURL(); url != "/users?status=not.eq.inactive" {
+		t.Errorf("BuildURL() = %v, want %v", url, "/users?status=not.eq.inactive")
+	}
+}
+
+func TestInsertReturningSelect(t *testing.T) {
+	var query url.Values
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`[{"id":7,"created_at":"2024-01-01T00:00:00Z"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var created []TestUser
+	err := client.Table("users").
+		Select("id", "created_at").
+		InsertReturning(TestUser{Name: "Alice"}, &created)
+	if err != nil {
+		t.Fatalf("InsertReturning() error = %v", err)
+	}
+
+	if query.Get("select") != "id,created_at" {
+		t.Errorf("select = %q, want %q", query.Get("select"), "id,created_at")
+	}
+	if prefer != "return=representation" {
+		t.Errorf("Prefer = %q, want %q", prefer, "return=representation")
+	}
+	if len(created) != 1 || created[0].ID != 7 || created[0].CreatedAt == "" {
+		t.Errorf("InsertReturning() = %v, want id 7 with created_at", created)
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		denied       bool
+		unauthorized bool
+	}{
+		{
+			name:   "forbidden status",
+			status: http.StatusForbidden,
+			body:   `{"message":"forbidden"}`,
+			denied: true,
+		},
+		{
+			name:         "unauthorized status",
+			status:       http.StatusUnauthorized,
+			body:         `{"code":"PGRST301","message":"JWT expired"}`,
+			unauthorized: true,
+		},
+		{
+			name:   "insufficient privilege code",
+			status: http.StatusBadRequest,
+			body:   `{"code":"42501","message":"new row violates row-level security policy for table \"users\""}`,
+			denied: true,
+		},
+		{
+			name:   "other error",
+			status: http.StatusBadRequest,
+			body:   `{"code":"22P02","message":"invalid input syntax for type integer"}`,
+			denied: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := New(server.URL, "fake-api-key")
+			err := client.Table("users").Insert(map[string]interface{}{"name": "John"})
+			if err == nil {
+				t.Fatal("Insert() should fail")
+			}
+
+			if errors.Is(err, ErrPermissionDenied) != tt.denied {
+				t.Errorf("errors.Is(%v, ErrPermissionDenied) = %v, want %v", err, !tt.denied, tt.denied)
+			}
+			if errors.Is(err, ErrUnauthorized) != tt.unauthorized {
+				t.Errorf("errors.Is(%v, ErrUnauthorized) = %v, want %v", err, !tt.unauthorized, tt.unauthorized)
+			}
+		})
+	}
+}
+
+func TestSelectJSONAs(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*QueryBuilder)
+		expected string
+	}{
+		{
+			name: "top-level key",
+			setup: func(qb *QueryBuilder) {
+				qb.SelectJSONAs("tier", "metadata", "tier")
+			},
+			expected: "/users?select=tier:metadata->>tier",
+		},
+		{
+			name: "nested key after columns",
+			setup: func(qb *QueryBuilder) {
+				qb.Select("id", "name").SelectJSONAs("theme", "metadata", "settings->theme")
+			},
+			expected: "/users?select=id,name,theme:metadata->settings->>theme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewQueryBuilder("users")
+			tt.setup(qb)
+
+			url := qb.BuildURL()
+			if url != tt.expected {
+				t.Errorf("BuildURL() = %v, want %v", url, tt.expected)
+			}
+		})
+	}
+
+	for _, args := range [][3]string{
+		{"bad alias", "metadata", "tier"},
+		{"tier", "metadata", "a->>b"},
+		{"tier", "metadata", ""},
+	} {
+		qb := NewQueryBuilder("users")
+		qb.SelectJSONAs(args[0], args[1], args[2])
+		if len(qb.Validate()) == 0 {
+			t.Errorf("SelectJSONAs(%q, %q, %q) should fail validation", args[0], args[1], args[2])
+		}
+	}
+}
+
+func TestWhereIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{"ints", NewQueryBuilder("users").WhereIn("id", 1, 2, 3), "id=in.(1,2,3)"},
+		{"strings", NewQueryBuilder("users").WhereIn("status", "active", "pending"), "status=in.(active,pending)"},
+		{"mixed types", NewQueryBuilder("users").WhereIn("code", 1, "two", 3.5, true), "code=in.(1,two,3.5,true)"},
+		{"reserved characters", NewQueryBuilder("users").WhereIn("name", "Doe, John", `say "hi"`, "a.b"), `name=in.("Doe, John","say \"hi\"","a.b")`},
+		{"not in", NewQueryBuilder("users").WhereNotIn("status", "banned", "deleted"), "status=not.in.(banned,deleted)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := tt.builder.allFilters()
+			if len(filters) != 1 || filters[0] != tt.expected {
+				t.Errorf("filters = %v, want [%s]", filters, tt.expected)
+			}
+			if errs := tt.builder.Validate(); len(errs) != 0 {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+}
+
+func TestWhereDistinctFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{"value", NewQueryBuilder("users").WhereDistinctFrom("status", "archived"), "status=isdistinct.archived"},
+		{"number", NewQueryBuilder("users").WhereDistinctFrom("score", 0), "score=isdistinct.0"},
+		{"null", NewQueryBuilder("users").WhereDistinctFrom("deleted_at", nil), "deleted_at=isdistinct.null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := tt.builder.allFilters()
+			if len(filters) != 1 || filters[0] != tt.expected {
+				t.Errorf("filters = %v, want [%s]", filters, tt.expected)
+			}
+			if errs := tt.builder.Validate(); len(errs) != 0 {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+}
+
+func TestWhereEqAnyAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{"any strings", NewQueryBuilder("posts").WhereEqAny("tag", "go", "rust"), "tag=eq(any).{go,rust}"},
+		{"any numbers", NewQueryBuilder("posts").WhereEqAny("id", 1, 2, 3), "id=eq(any).{1,2,3}"},
+		{"all", NewQueryBuilder("posts").WhereEqAll("status", "draft"), "status=eq(all).{draft}"},
+		{"escaped", NewQueryBuilder("posts").WhereEqAny("title", "a,b", `say "hi"`, "{x}", "", "null"), `title=eq(any).{"a,b","say \"hi\"","{x}","","null"}`},
+		{"nil", NewQueryBuilder("posts").WhereEqAny("tag", "go", nil), "tag=eq(any).{go,NULL}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := tt.builder.allFilters()
+			if len(filters) != 1 || filters[0] != tt.expected {
+				t.Errorf("filters = %v, want [%s]", filters, tt.expected)
+			}
+			if errs := tt.builder.Validate(); len(errs) != 0 {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+}
+
+func TestWhereIs(t *testing.T) {
+	verified := true
+	var unset *bool
+
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{"eq true", NewQueryBuilder("users").Where("active", "eq", true), "active=eq.true"},
+		{"eq false", NewQueryBuilder("users").Where("active", "eq", false), "active=eq.false"},
+		{"is true", NewQueryBuilder("users").WhereIs("active", true), "active=is.true"},
+		{"is false", NewQueryBuilder("users").WhereIs("active", false), "active=is.false"},
+		{"is null", NewQueryBuilder("users").WhereIs("deleted_at", nil), "deleted_at=is.null"},
+		{"bool pointer", NewQueryBuilder("users").WhereIs("verified", &verified), "verified=is.true"},
+		{"nil bool pointer", NewQueryBuilder("users").WhereIs("verified", unset), "verified=is.null"},
+		{"unknown", NewQueryBuilder("users").WhereIs("verified", "UNKNOWN"), "verified=is.unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := tt.builder.allFilters()
+			if len(filters) != 1 || filters[0] != tt.expected {
+				t.Errorf("filters = %v, want [%s]", filters, tt.expected)
+			}
+			if errs := tt.builder.Validate(); len(errs) != 0 {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+
+	for _, value := range []interface{}{1, "yes"} {
+		if errs := NewQueryBuilder("users").WhereIs("active", value).Validate(); len(errs) != 1 {
+			t.Errorf("WhereIs(%v).Validate() = %v, want one error", value, errs)
+		}
+	}
+}
+
+func TestReturnHeadersOnly(t *testing.T) {
+	var prefer string
+	location := "/users?id=eq.42"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	type User struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	user := User{Name: "Ann"}
+	if err := client.Table("users").ReturnHeadersOnly().Insert(&user); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if prefer != "return=headers-only" {
+		t.Errorf("Prefer = %q, want return=headers-only", prefer)
+	}
+	if user.ID != 42 || user.Name != "Ann" {
+		t.Errorf("user = %+v, want ID 42 and the name sent", user)
+	}
+
+	// Composite and text keys, including one that looks like a number
+	location = "/memberships?org_id=eq.0f8fad5b-d9cb-469f-a165-70867728950e&code=eq.123"
+	type Membership struct {
+		OrgID string `json:"org_id"`
+		Code  string `json:"code"`
+	}
+	var membership Membership
+	if err := client.Table("memberships").ReturnHeadersOnly().Insert(&membership); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if membership.OrgID != "0f8fad5b-d9cb-469f-a165-70867728950e" || membership.Code != "123" {
+		t.Errorf("membership = %+v", membership)
+	}
+
+	if err := client.Table("users").ReturnHeadersOnly().Insert(&[]User{{Name: "a"}, {Name: "b"}}); err == nil {
+		t.Error("Insert() of several rows with ReturnHeadersOnly succeeded, want an error")
+	}
+
+	location = ""
+	if err := client.Table("users").ReturnHeadersOnly().Insert(&user); err == nil {
+		t.Error("Insert() without a Location succeeded, want an error")
+	}
+}
+
+func TestUpdateIf(t *testing.T) {
+	var query url.Values
+	var prefer, body string
+	version := 3
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		prefer = r.Header.Get("Prefer")
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+
+		w.Header().Set("Content-Type", "application/json")
+		if query.Get("version") != fmt.Sprintf("eq.%d", version) {
+			w.Write([]byte(`[]`))
+			return
+		}
+		version++
+		w.Write([]byte(fmt.Sprintf(`[{"id":1,"title":"New","version":%d}]`, version)))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	type Doc struct {
+		ID      int    `json:"id,omitempty"`
+		Title   string `json:"title"`
+		Version int    `json:"version"`
+	}
+
+	doc := Doc{Title: "New", Version: 4}
+	updated, err := client.Table("docs").Where("id", "eq", 1).UpdateIf("version", "eq", 3, &doc)
+	if err != nil {
+		t.Fatalf("UpdateIf() error = %v", err)
+	}
+	if !updated {
+		t.Error("UpdateIf() = false, want true when the condition matches")
+	}
+	if query.Get("id") != "eq.1" || query.Get("version") != "eq.3" {
+		t.Errorf("filters = %v, want id=eq.1 and version=eq.3", query)
+	}
+	if prefer != "return=representation" || body != `{"title":"New","version":4}` {
+		t.Errorf("Prefer = %q, body = %s", prefer, body)
+	}
+	if doc.ID != 1 || doc.Version != 4 {
+		t.Errorf("doc = %+v, want the updated row", doc)
+	}
+
+	// A stale version matches nothing
+	stale := map[string]interface{}{"title": "Stale", "version": 4}
+	updated, err = client.Table("docs").Where("id", "eq", 1).UpdateIf("version", "eq", 3, stale)
+	if err != nil {
+		t.Fatalf("UpdateIf() error = %v", err)
+	}
+	if updated {
+		t.Error("UpdateIf() = true, want false when the condition doesn't match")
+	}
+}
+
+func TestRPCRaw(t *testing.T) {
+	var path, accept, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		accept = r.Header.Get("Accept")
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("month,total\n2024-01,120\n2024-02,95\n"))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	report, err := client.RPCRaw(context.Background(), "monthly_report", map[string]interface{}{"year": 2024}, "text/csv")
+	if err != nil {
+		t.Fatalf("RPCRaw() error = %v", err)
+	}
+
+	if path != "/rest/v1/rpc/monthly_report" {
+		t.Errorf("path = %q, want /rest/v1/rpc/monthly_report", path)
+	}
+	if accept != "text/csv" {
+		t.Errorf("Accept = %q, want text/csv", accept)
+	}
+	if body != `{"year":2024}` {
+		t.Errorf("body = %s, want the params", body)
+	}
+	if string(report) != "month,total\n2024-01,120\n2024-02,95\n" {
+		t.Errorf("RPCRaw() = %q, want the CSV unchanged", report)
+	}
+}
+
+func TestGetScalar(t *testing.T) {
+	var query url.Values
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	body = `[{"max":42}]`
+	var maxAge int
+	if err := client.Table("users").Select("age.max()").GetScalar(&maxAge); err != nil {
+		t.Fatalf("GetScalar() error = %v", err)
+	}
+	if maxAge != 42 {
+		t.Errorf("GetScalar() = %d, want 42", maxAge)
+	}
+	if got := query.Get("select"); got != "age.max()" {
+		t.Errorf("select = %q, want %q", got, "age.max()")
+	}
+
+	body = `[{"max":"2024-03-01T12:30:00Z"}]`
+	var latest time.Time
+	if err := client.Table("users").Select("created_at.max()").GetScalar(&latest); err != nil {
+		t.Fatalf("GetScalar() error = %v", err)
+	}
+	if want := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC); !latest.Equal(want) {
+		t.Errorf("GetScalar() = %v, want %v", latest, want)
+	}
+
+	body = `[{"id":1},{"id":2}]`
+	var id int
+	if err := client.Table("users").Select("id").GetScalar(&id); err == nil {
+		t.Error("GetScalar() with two rows error = nil, want error")
+	}
+
+	body = `[{"id":1,"name":"John"}]`
+	if err := client.Table("users").Select("id", "name").GetScalar(&id); err == nil {
+		t.Error("GetScalar() with two columns error = nil, want error")
+	}
+}
+
+func TestRestrictColumns(t *testing.T) {
+	allowed := []string{"id", "name", "age", "metadata"}
+
+	valid := NewQueryBuilder("users").
+		RestrictColumns(allowed...).
+		Select("id", "name", "tier:metadata->>tier").
+		Where("age", "gte", 18).
+		OrWhere("name", "eq", "John").
+		Order("name", "asc")
+	if errs := valid.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+
+	tests := []struct {
+		name    string
+		builder *QueryBuilder
+	}{
+		{"filter", NewQueryBuilder("users").Where("password_hash", "eq", "x")},
+		{"filter in group", NewQueryBuilder("users").Or("age.gt.18", "password_hash.eq.x")},
+		{"negated filter", NewQueryBuilder("users").Not("email", "eq", "a@example.com")},
+		{"order", NewQueryBuilder("users").Order("created_at", "desc")},
+		{"select", NewQueryBuilder("users").Select("id", "password_hash")},
+		{"select all", NewQueryBuilder("users").Select("*")},
+		{"embed", NewQueryBuilder("users").SelectCount("posts")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.builder.RestrictColumns(allowed...).Validate()
+			if len(errs) != 1 {
+				t.Errorf("Validate() = %v, want one error", errs)
+			}
+		})
+	}
+}
+
+func TestWhereCast(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	qb := client.Table("products").WhereCast("price", "numeric", "gt", 10)
+	if url := qb.BuildURL(); url != "/products?price::numeric=gt.10" {
+		t.Errorf("BuildURL() = %v, want %v", url, "/products?price::numeric=gt.10")
+	}
+
+	var rows []map[string]interface{}
+	if err := qb.Get(&rows); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := query.Get("price::numeric"); got != "gt.10" {
+		t.Errorf("price::numeric = %q, want %q", got, "gt.10")
+	}
+
+	invalid := client.Table("products").WhereCast("price", "numeric;drop", "gt", 10)
+	if errs := invalid.Validate(); len(errs) != 1 {
+		t.Errorf("Validate() = %v, want one error", errs)
+	}
+}
+
+func TestNoContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	user := &TestUser{Name: "Alice"}
+	if err := client.Table("users").Header("Prefer", "return=minimal").Insert(user); err != nil {
+		t.Errorf("Insert() error = %v", err)
+	}
+	if user.Name != "Alice" {
+		t.Errorf("Insert() modified data = %+v", user)
+	}
+
+	var result map[string]interface{}
+	if err := client.RPC("touch", nil, &result); err != nil {
+		t.Errorf("RPC() error = %v", err)
+	}
+}
+
+func TestMaxAffected(t *testing.T) {
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"PGRST124","message":"Query result exceeds max-affected preference constraint","details":"The query affects 12 rows","hint":null}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	err := client.Table("users").Where("status", "eq", "inactive").MaxAffected(5).Delete()
+	if !errors.Is(err, ErrTooManyAffected) {
+		t.Errorf("Delete() error = %v, want ErrTooManyAffected", err)
+	}
+	if prefer != "handling=strict, max-affected=5" {
+		t.Errorf("Prefer = %q, want %q", prefer, "handling=strict, max-affected=5")
+	}
+
+	var deleted []TestUser
+	err = client.Table("users").Where("status", "eq", "inactive").MaxAffected(5).DeleteReturning(&deleted)
+	if !errors.Is(err, ErrTooManyAffected) {
+		t.Errorf("DeleteReturning() error = %v, want ErrTooManyAffected", err)
+	}
+	if prefer != "handling=strict, max-affected=5, return=representation" {
+		t.Errorf("Prefer = %q, want preferences combined", prefer)
+	}
+	if errors.Is(err, ErrPermissionDenied) {
+		t.Error("errors.Is(err, ErrPermissionDenied) = true, want false")
+	}
+}
+
+func TestLimitZero(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var users []TestUser
+	if err := client.Table("users").Select("id").Limit(0).Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if !query.Has("limit") || query.Get("limit") != "0" {
+		t.Errorf("query = %v, want limit=0", query)
+	}
+	if users == nil || len(users) != 0 {
+		t.Errorf("Get() = %#v, want an empty slice", users)
+	}
+
+	// A maximum limit doesn't replace an explicit zero
+	if err := client.WithMaxLimit(100).Table("users").Limit(0).Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if query.Get("limit") != "0" {
+		t.Errorf("limit = %q, want 0", query.Get("limit"))
+	}
+}
+
+func TestGetPaginated(t *testing.T) {
+	var rangeHeader, prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader = r.Header.Get("Range")
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Range", "20-21/45")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(`[{"id":21,"name":"John"},{"id":22,"name":"Jane"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var users []TestUser
+	page, err := client.Table("users").Order("id", "asc").GetPaginated(context.Background(), 3, 10, &users)
+	if err != nil {
+		t.Fatalf("GetPaginated() error = %v", err)
+	}
+
+	if rangeHeader != "20-29" {
+		t.Errorf("Range = %q, want %q", rangeHeader, "20-29")
+	}
+	if prefer != "count=exact" {
+		t.Errorf("Prefer = %q, want %q", prefer, "count=exact")
+	}
+
+	if page.Total != 45 || page.Page != 3 || page.PageSize != 10 || !page.HasMore {
+		t.Errorf("GetPaginated() = %+v, want total 45, page 3, size 10, more", page)
+	}
+	if len(users) != 2 || users[0].ID != 21 {
+		t.Errorf("GetPaginated() rows = %+v", users)
+	}
+
+	envelope, _ := json.Marshal(&Page{Data: []int{}, Total: 45, Page: 5, PageSize: 10})
+	if string(envelope) != `{"data":[],"total":45,"page":5,"pageSize":10,"hasMore":false}` {
+		t.Errorf("json.Marshal(Page) = %s", envelope)
+	}
+
+	if _, err := client.Table("users").GetPaginated(context.Background(), 0, 10, &users); err == nil {
+		t.Error("GetPaginated() with page 0 error = nil, want error")
+	}
+}
+
+func TestContextDeadlineTimeout(t *testing.T) {
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var users []TestUser
+	if err := client.Table("users").WithContext(ctx).Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if prefer != "timeout=5" {
+		t.Errorf("Prefer = %q, want %q", prefer, "timeout=5")
+	}
+
+	if err := client.Table("users").Where("id", "eq", 1).MaxAffected(1).WithContext(ctx).Delete(); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if prefer != "handling=strict, max-affected=1, timeout=5" {
+		t.Errorf("Prefer = %q, want the timeout appended", prefer)
+	}
+
+	if err := client.Table("users").WithContext(context.Background()).Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if prefer != "" {
+		t.Errorf("Prefer = %q, want none without a deadline", prefer)
+	}
+}
+
+func TestTimeoutPrecedence(t *testing.T) {
+	// Handlers of timed out requests keep running, so prefer is guarded
+	var mu sync.Mutex
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		prefer = r.Header.Get("Prefer")
+		mu.Unlock()
+		if r.URL.Path == "/rest/v1/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key", WithHTTPTimeout(30*time.Second))
+
+	short, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		builder *QueryBuilder
+		want    string
+	}{
+		{"client default", client.Table("users"), "timeout=30"},
+		{"longer query timeout overrides", client.Table("users").Timeout(2 * time.Minute), "timeout=120"},
+		{"shorter query timeout overrides", client.Table("users").Timeout(5 * time.Second), "timeout=5"},
+		{"shorter context deadline wins", client.Table("users").WithContext(short).Timeout(time.Minute), "timeout=3"},
+		{"no timeout", New(server.URL, "fake-api-key").Table("users"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []map[string]interface{}
+			if err := tt.builder.Get(&rows); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			mu.Lock()
+			got := prefer
+			mu.Unlock()
+			if got != tt.want {
+				t.Errorf("Prefer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The effective deadline is enforced on the request
+	var rows []map[string]interface{}
+	fast := New(server.URL, "fake-api-key", WithHTTPTimeout(50*time.Millisecond))
+	if err := fast.Table("slow").Get(&rows); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() past the client default = %v, want context.DeadlineExceeded", err)
+	}
+	if err := fast.Table("slow").Timeout(5 * time.Second).Get(&rows); err != nil {
+		t.Errorf("Get() with a longer query timeout = %v, want no error", err)
+	}
+	if err := client.Table("slow").Timeout(50 * time.Millisecond).Get(&rows); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() past the query timeout = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestUpdateJSON(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	err := client.Table("profiles").
+		Where("id", "eq", 7).
+		UpdateJSON("metadata", map[string]interface{}{"theme": "dark"})
+	if err != nil {
+		t.Fatalf("UpdateJSON() error = %v", err)
+	}
+
+	if path != "/rest/v1/rpc/"+JSONMergeFunction {
+		t.Errorf("path = %q, want the merge function", path)
+	}
+
+	expected := map[string]interface{}{
+		"p_table":   "profiles",
+		"p_column":  "metadata",
+		"p_patch":   map[string]interface{}{"theme": "dark"},
+		"p_filters": []interface{}{map[string]interface{}{"column": "id", "operator": "eq", "value": "7"}},
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("body = %v, want %v", body, expected)
+	}
+
+	// Mixed-case and reserved columns are quoted in the URL but not for the function
+	err = client.Table("profiles").
+		Where("userId", "eq", 7).
+		Where("order", "gt", 1).
+		UpdateJSON("metadata", map[string]interface{}{"theme": "dark"})
+	if err != nil {
+		t.Fatalf("UpdateJSON() with quoted columns error = %v", err)
+	}
+	wantFilters := []interface{}{
+		map[string]interface{}{"column": "userId", "operator": "eq", "value": "7"},
+		map[string]interface{}{"column": "order", "operator": "gt", "value": "1"},
+	}
+	if !reflect.DeepEqual(body["p_filters"], wantFilters) {
+		t.Errorf("p_filters = %v, want %v", body["p_filters"], wantFilters)
+	}
+
+	unsupported := []*QueryBuilder{
+		client.Table("profiles"),
+		client.Table("profiles").Where("name", "like", "J%"),
+		client.Table("profiles").Or("id.eq.1", "id.eq.2"),
+		client.FromView("active_profiles").Where("id", "eq", 7),
+	}
+	for _, qb := range unsupported {
+		if err := qb.UpdateJSON("metadata", map[string]interface{}{"theme": "dark"}); err == nil {
+			t.Errorf("UpdateJSON() on %s error = nil, want error", qb.BuildURL())
+		}
+	}
+}
+
+func TestPatchJSON(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	err := client.Table("documents").
+		Where("id", "eq", 7).
+		PatchJSON("doc", []JSONPatchOp{
+			{Op: "add", Path: "/tags/-", Value: "go"},
+			{Op: "replace", Path: "/title", Value: "Hello"},
+			{Op: "add", Path: "/archived_at", Value: nil},
+		})
+	if err != nil {
+		t.Fatalf("PatchJSON() error = %v", err)
+	}
+
+	if path != "/rest/v1/rpc/"+JSONPatchFunction {
+		t.Errorf("path = %q, want the patch function", path)
+	}
+
+	expected := map[string]interface{}{
+		"p_table":  "documents",
+		"p_column": "doc",
+		"p_ops": []interface{}{
+			map[string]interface{}{"op": "add", "path": "/tags/-", "value": "go"},
+			map[string]interface{}{"op": "replace", "path": "/title", "value": "Hello"},
+			map[string]interface{}{"op": "add", "path": "/archived_at", "value": nil},
+		},
+		"p_filters": []interface{}{map[string]interface{}{"column": "id", "operator": "eq", "value": "7"}},
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("body = %v, want %v", body, expected)
+	}
+
+	raw, _ := json.Marshal(JSONPatchOp{Op: "move", From: "/draft", Path: "/title"})
+	if string(raw) != `{"op":"move","path":"/title","from":"/draft"}` {
+		t.Errorf("move op = %s", raw)
+	}
+
+	invalid := []struct {
+		builder *QueryBuilder
+		ops     []JSONPatchOp
+	}{
+		{client.Table("documents"), []JSONPatchOp{{Op: "add", Path: "/a", Value: 1}}},
+		{client.Table("documents").Where("id", "eq", 7), nil},
+		{client.Table("documents").Where("id", "eq", 7), []JSONPatchOp{{Op: "merge", Path: "/a"}}},
+		{client.Table("documents").Where("id", "eq", 7), []JSONPatchOp{{Op: "add", Path: "a", Value: 1}}},
+		{client.Table("documents").Where("id", "eq", 7), []JSONPatchOp{{Op: "copy", Path: "/a"}}},
+	}
+	for i, tt := range invalid {
+		if err := tt.builder.PatchJSON("doc", tt.ops); err == nil {
+			t.Errorf("invalid[%d]: PatchJSON() error = nil, want error", i)
+		}
+	}
+}
+
+func TestJSONUpdatesUseQuerySettings(t *testing.T) {
+	reads := 0
+	var rawQuery, requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			reads++
+			w.Write([]byte(`[{"id":7}]`))
+			return
+		}
+		rawQuery = r.URL.RawQuery
+		requestID = r.Header.Get("X-Request-Id")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ops := []JSONPatchOp{{Op: "replace", Path: "/title", Value: "Hello"}}
+	updates := map[string]func(*QueryBuilder) error{
+		"UpdateJSON": func(q *QueryBuilder) error {
+			return q.UpdateJSON("doc", map[string]interface{}{"title": "Hello"})
+		},
+		"PatchJSON": func(q *QueryBuilder) error { return q.PatchJSON("doc", ops) },
+	}
+
+	for name, update := range updates {
+		t.Run(name, func(t *testing.T) {
+			client := New(server.URL, "fake-api-key").WithQueryCache(time.Minute, 10)
+			read := func() {
+				var rows []map[string]interface{}
+				if err := client.Table("documents").Get(&rows); err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+			}
+
+			reads = 0
+			read()
+			read()
+
+			err := update(client.Table("documents").Header("X-Request-Id", "req-1").Where("id", "eq", 7))
+			if err != nil {
+				t.Fatalf("%s() error = %v", name, err)
+			}
+			if requestID != "req-1" || rawQuery != "" {
+				t.Errorf("X-Request-Id = %q, query = %q, want the builder's header and no parameters", requestID, rawQuery)
+			}
+
+			// Cached reads of the table are dropped by the write
+			read()
+			if reads != 2 {
+				t.Errorf("reads = %d, want 2", reads)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err = update(client.Table("documents").WithContext(ctx).Where("id", "eq", 7))
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("%s() with a cancelled context = %v, want context.Canceled", name, err)
+			}
+		})
+	}
+}
+
+func TestCountModes(t *testing.T) {
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{"exact", client.Table("users").Count(), "count=exact"},
+		{"planned", client.Table("users").CountPlanned(), "count=planned"},
+		{"estimated", client.Table("users").CountEstimated(), "count=estimated"},
+		{"mode", client.Table("users").CountMode(CountPlanned), "count=planned"},
+		{"with other preferences", client.Table("users").Header("Prefer", "missing=null").CountEstimated(), "missing=null, count=estimated"},
+		{"none", client.Table("users"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []TestUser
+			if err := tt.builder.Get(&users); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if prefer != tt.expected {
+				t.Errorf("Prefer = %q, want %q", prefer, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetWithCount(t *testing.T) {
+	var prefer string
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		if query.Get("limit") == "0" {
+			w.Header().Set("Content-Range", "*/42")
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Header().Set("Content-Range", "0-1/42")
+		w.Write([]byte(`[{"id":1,"name":"John"},{"id":2,"name":"Jane"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var users []TestUser
+	total, err := client.Table("users").GetWithCount(context.Background(), &users)
+	if err != nil {
+		t.Fatalf("GetWithCount() error = %v", err)
+	}
+	if total != 42 || len(users) != 2 {
+		t.Errorf("GetWithCount() = %d with %d rows, want 42 with 2 rows", total, len(users))
+	}
+	if prefer != "count=exact" {
+		t.Errorf("Prefer = %q, want %q", prefer, "count=exact")
+	}
+	if query.Has("count") {
+		t.Errorf("query = %v, want no count parameter", query)
+	}
+
+	total, err = client.Table("users").Limit(0).CountPlanned().GetWithCount(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetWithCount() error = %v", err)
+	}
+	if total != 42 || prefer != "count=planned" {
+		t.Errorf("GetWithCount() = %d with Prefer %q, want 42 with count=planned", total, prefer)
+	}
+
+	resp := &Response{Headers: map[string]string{"Content-Range": "10-19/250"}}
+	if start, end, total := resp.GetContentRange(); start != 10 || end != 19 || total != 250 {
+		t.Errorf("GetContentRange() = %d, %d, %d, want 10, 19, 250", start, end, total)
+	}
+}
+
+func TestWhereStruct(t *testing.T) {
+	type Pagination struct {
+		Cursor int `supabase:"id,gt"`
+	}
+	type UserSearch struct {
+		Name     string   `supabase:"name,ilike"`
+		MinAge   int      `supabase:"age,gte"`
+		Status   []string `supabase:"status,in"`
+		Active   *bool    `supabase:"active"`
+		Verified *bool    `supabase:"verified"`
+		Email    string   `supabase:"email"`
+		Role     string   `supabase:"role,omitzero"`
+		Score    int      `supabase:"score,gt,omitzero"`
+		Note     string   `supabase:"omitzero"`
+		Internal string
+		*Pagination
+	}
+
+	inactive := false
+	search := UserSearch{
+		Name:     "%john%",
+		Status:   []string{"active", "pending"},
+		Active:   &inactive,
+		Role:     "admin",
+		Score:    10,
+		Note:     "ignored",
+		Internal: "ignored",
+	}
+
+	qb := NewQueryBuilder("users").WhereStruct(&search)
+	expected := []string{"name=ilike.%john%", "status=in.(active,pending)", "active=eq.false", "role=eq.admin", "score=gt.10"}
+	if !reflect.DeepEqual(qb.filters, expected) {
+		t.Errorf("WhereStruct() = %v, want %v", qb.filters, expected)
+	}
+
+	search.Pagination = &Pagination{Cursor: 100}
+	qb = NewQueryBuilder("users").WhereStruct(search)
+	if got := qb.filters[len(qb.filters)-1]; got != "id=gt.100" {
+		t.Errorf("WhereStruct() last filter = %v, want id=gt.100", got)
+	}
+
+	if errs := NewQueryBuilder("users").WhereStruct("name").Validate(); len(errs) != 1 {
+		t.Errorf("Validate() = %v, want one error", errs)
+	}
+}
+
+func TestDistinctValues(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"status":"active"},{"status":"active"},{"status":"banned"},{"status":null},{"status":"pending"},{"status":"banned"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var statuses []string
+	err := client.Table("users").Order("status", "asc").DistinctValues(context.Background(), "status", &statuses)
+	if err != nil {
+		t.Fatalf("DistinctValues() error = %v", err)
+	}
+
+	if expected := []string{"active", "banned", "pending"}; !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("DistinctValues() = %v, want %v", statuses, expected)
+	}
+	if query.Get("select") != "status" || query.Get("order") != "status.asc" {
+		t.Errorf("query = %v, want select=status&order=status.asc", query)
+	}
+
+	if err := client.Table("users").DistinctValues(context.Background(), "status;", &statuses); err == nil {
+		t.Error("DistinctValues() with invalid column error = nil, want error")
+	}
+}
+
+func TestInsertIfNotExists(t *testing.T) {
+	existing := map[string]TestUser{"john@example.com": {ID: 1, Name: "John", Email: "john@example.com"}}
+	var insertQuery, lookupQuery url.Values
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			insertQuery = r.URL.Query()
+			prefer = r.Header.Get("Prefer")
+			var user TestUser
+			json.NewDecoder(r.Body).Decode(&user)
+			if _, ok := existing[user.Email]; ok {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`[]`))
+				return
+			}
+			user.ID = len(existing) + 1
+			existing[user.Email] = user
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode([]TestUser{user})
+		case http.MethodGet:
+			lookupQuery = r.URL.Query()
+			email := strings.TrimPrefix(lookupQuery.Get("email"), "eq.")
+			json.NewEncoder(w).Encode([]TestUser{existing[email]})
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	user := &TestUser{Name: "Jane", Email: "jane@example.com"}
+	inserted, err := client.Table("users").InsertIfNotExists(user, "email")
+	if err != nil {
+		t.Fatalf("InsertIfNotExists() error = %v", err)
+	}
+	if !inserted || user.ID != 2 {
+		t.Errorf("InsertIfNotExists() = %v with %+v, want inserted row with id 2", inserted, user)
+	}
+	if insertQuery.Get("on_conflict") != "email" {
+		t.Errorf("on_conflict = %q, want email", insertQuery.Get("on_conflict"))
+	}
+	if prefer != "resolution=ignore-duplicates, return=representation" {
+		t.Errorf("Prefer = %q", prefer)
+	}
+
+	user = &TestUser{Name: "Johnny", Email: "john@example.com"}
+	inserted, err = client.Table("users").InsertIfNotExists(user, "email")
+	if err != nil {
+		t.Fatalf("InsertIfNotExists() error = %v", err)
+	}
+	if inserted || user.ID != 1 || user.Name != "John" {
+		t.Errorf("InsertIfNotExists() = %v with %+v, want existing row John with id 1", inserted, user)
+	}
+	if lookupQuery.Get("email") != "eq.john@example.com" || lookupQuery.Get("limit") != "1" {
+		t.Errorf("lookup query = %v", lookupQuery)
+	}
+
+	if _, err := client.Table("users").InsertIfNotExists(TestUser{}, "email"); err == nil {
+		t.Error("InsertIfNotExists() with a non-pointer error = nil, want error")
+	}
+}
+
+func TestEmbedWithFK(t *testing.T) {
+	qb := NewQueryBuilder("posts").
+		Select("id", "title").
+		EmbedWithFK("author", "users", "author_id", "id", "name").
+		EmbedWithFK("reviewer", "users", "reviewer_id")
+
+	expected := "/posts?select=id,title,author:users!author_id(id,name),reviewer:users!reviewer_id(*)"
+	if url := qb.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %v, want %v", url, expected)
+	}
+
+	if errs := qb.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+
+	unaliased := NewQueryBuilder("posts").EmbedWithFK("", "users", "author_id")
+	if url := unaliased.BuildURL(); url != "/posts?select=*,users!author_id(*)" {
+		t.Errorf("BuildURL() without alias = %v", url)
+	}
+
+	for _, invalid := range []*QueryBuilder{
+		NewQueryBuilder("posts").EmbedWithFK("author", "users", "author_id(*)"),
+		NewQueryBuilder("posts").EmbedWithFK("author:x", "users", "author_id"),
+	} {
+		if errs := invalid.Validate(); len(errs) != 1 {
+			t.Errorf("Validate() = %v, want one error", errs)
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	var query url.Values
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		prefer = r.Header.Get("Prefer")
+
+		var users []TestUser
+		json.NewDecoder(r.Body).Decode(&users)
+		for i := range users {
+			users[i].CreatedAt = fmt.Sprintf("2024-01-0%dT00:00:00Z", i+1)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(users)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	users := []TestUser{
+		{ID: 1, Name: "John", Email: "john@example.com"},
+		{ID: 2, Name: "Jane", Email: "jane@example.com"},
+	}
+	if err := client.Table("users").OnConflict("email").Upsert(&users); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	if query.Get("on_conflict") != "email" {
+		t.Errorf("on_conflict = %q, want email", query.Get("on_conflict"))
+	}
+	if prefer != "resolution=merge-duplicates, return=representation" {
+		t.Errorf("Prefer = %q", prefer)
+	}
+	for i, user := range users {
+		want := fmt.Sprintf("2024-01-0%dT00:00:00Z", i+1)
+		if user.ID != i+1 || user.CreatedAt != want {
+			t.Errorf("users[%d] = %+v, want id %d created at %s", i, user, i+1, want)
+		}
+	}
+}
+
+func TestCountContext(t *testing.T) {
+	var method, prefer string
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		prefer = r.Header.Get("Prefer")
+		query = r.URL.Query()
+
+		w.Header().Set("Content-Range", "0-24/25")
+		if query.Get("status") != "eq.active" {
+			w.Header().Set("Content-Range", "0-24/100")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	count, err := client.Table("users").
+		Where("status", "eq", "active").
+		Where("age", "gte", 18).
+		CountContext(context.Background())
+	if err != nil {
+		t.Fatalf("CountContext() error = %v", err)
+	}
+
+	if count != 25 {
+		t.Errorf("CountContext() = %d, want 25", count)
+	}
+	if method != http.MethodHead {
+		t.Errorf("method = %s, want HEAD", method)
+	}
+	if prefer != "count=exact" {
+		t.Errorf("Prefer = %q, want count=exact", prefer)
+	}
+	if query.Get("age") != "gte.18" {
+		t.Errorf("age filter = %q, want gte.18", query.Get("age"))
+	}
+
+	if _, err := client.Table("users").CountPlanned().CountContext(context.Background()); err != nil {
+		t.Fatalf("CountContext() error = %v", err)
+	}
+	if prefer != "count=planned" {
+		t.Errorf("Prefer = %q, want count=planned", prefer)
+	}
+}
+
+func TestCountUnavailable(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentRange string
+	}{
+		{"unknown total", "0-9/*"},
+		{"empty result", "*/*"},
+		{"missing header", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentRange != "" {
+					w.Header().Set("Content-Range", tt.contentRange)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`[]`))
+			}))
+			defer server.Close()
+
+			client := New(server.URL, "fake-api-key")
+			ctx := context.Background()
+
+			if _, err := client.Table("users").CountContext(ctx); !errors.Is(err, ErrCountUnavailable) {
+				t.Errorf("CountContext() error = %v, want ErrCountUnavailable", err)
+			}
+			if _, err := client.Table("users").GetWithCount(ctx, nil); !errors.Is(err, ErrCountUnavailable) {
+				t.Errorf("GetWithCount() error = %v, want ErrCountUnavailable", err)
+			}
+			if _, err := GetResult[map[string]interface{}](ctx, client.Table("users")); !errors.Is(err, ErrCountUnavailable) {
+				t.Errorf("GetResult() error = %v, want ErrCountUnavailable", err)
+			}
+			var rows []map[string]interface{}
+			if _, err := client.Table("users").GetPaginated(ctx, 1, 10, &rows); !errors.Is(err, ErrCountUnavailable) {
+				t.Errorf("GetPaginated() error = %v, want ErrCountUnavailable", err)
+			}
+		})
+	}
+}
+
+func TestOrderSafe(t *testing.T) {
+	tests := []struct {
+		name      string
+		column    string
+		direction string
+		want      string
+	}{
+		{"ascending", "name", "asc", "order=name.asc"},
+		{"descending uppercase", "name", "DESC", "order=name.desc"},
+		{"empty direction", "name", "", "order=name.asc"},
+		{"unknown direction", "name", "sideways", "order=name.asc"},
+		{"injected modifier", "name", "desc.nullsfirst", "order=name.asc"},
+		{"injected term", "name", "desc,password_hash.asc", "order=name.asc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueryBuilder("users").OrderSafe(tt.column, tt.direction)
+			if errs := q.Validate(); len(errs) != 0 {
+				t.Fatalf("Validate() = %v, want no errors", errs)
+			}
+			if q.orderQuery != tt.want {
+				t.Errorf("orderQuery = %q, want %q", q.orderQuery, tt.want)
+			}
+		})
+	}
+
+	if errs := NewQueryBuilder("users").OrderSafe("name,id", "asc").Validate(); len(errs) != 1 {
+		t.Errorf("Validate() with an invalid column = %v, want one error", errs)
+	}
+
+	allowed := NewQueryBuilder("users").RestrictColumns("id", "name").OrderSafe("name", "desc")
+	if errs := allowed.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() with an allowed column = %v, want no errors", errs)
+	}
+
+	restricted := NewQueryBuilder("users").RestrictColumns("id", "name").OrderSafe("password_hash", "desc")
+	if errs := restricted.Validate(); len(errs) != 1 {
+		t.Errorf("Validate() with an off-allowlist column = %v, want one error", errs)
+	}
+}
+
+func TestBulkUpdate(t *testing.T) {
+	var requests []*http.Request
+	var bodies [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+
+		var rows []map[string]interface{}
+		raw, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			var row map[string]interface{}
+			json.Unmarshal(raw, &row)
+			rows = []map[string]interface{}{row}
+		}
+		bodies = append(bodies, rows)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "John", "age": 31},
+		{"id": 2, "name": "Jane", "age": 28},
+		{"id": 3, "name": "Jim", "age": 45},
+	}
+	affected, err := client.Table("users").FullRows().BulkUpdate(context.Background(), "id", rows)
+	if err != nil {
+		t.Fatalf("BulkUpdate() error = %v", err)
+	}
+
+	if affected != 3 {
+		t.Errorf("BulkUpdate() = %d, want 3", affected)
+	}
+	if len(requests) != 1 || requests[0].Method != http.MethodPost {
+		t.Fatalf("requests = %d, want a single POST", len(requests))
+	}
+	if got := requests[0].URL.Query().Get("on_conflict"); got != "id" {
+		t.Errorf("on_conflict = %q, want id", got)
+	}
+	if got := requests[0].Header.Get("Prefer"); got != "resolution=merge-duplicates, return=representation" {
+		t.Errorf("Prefer = %q", got)
+	}
+	for i, row := range bodies[0] {
+		if row["name"] != rows[i]["name"] || row["age"] != float64(rows[i]["age"].(int)) {
+			t.Errorf("row %d = %v, want %v", i, row, rows[i])
+		}
+	}
+
+	// Partial rows are patched by key, with differing or the same columns
+	partial := map[string][]map[string]interface{}{
+		"differing columns": {{"id": 1, "name": "John"}, {"id": 2, "age": 29}},
+		"same columns":      {{"id": 1, "age": 32}, {"id": 2, "age": 29}},
+	}
+	for name, rows := range partial {
+		requests, bodies = nil, nil
+		affected, err = client.Table("users").Where("active", "eq", true).BulkUpdate(context.Background(), "id", rows)
+		if err != nil {
+			t.Fatalf("BulkUpdate() with %s error = %v", name, err)
+		}
+		if affected != 2 || len(requests) != 2 {
+			t.Fatalf("BulkUpdate() with %s = %d in %d requests, want 2 in 2", name, affected, len(requests))
+		}
+		for i, r := range requests {
+			query := r.URL.Query()
+			if r.Method != http.MethodPatch || query.Get("id") != fmt.Sprintf("eq.%d", i+1) || query.Get("active") != "eq.true" {
+				t.Errorf("%s: request %d = %s %s, want PATCH filtered by id and active", name, i, r.Method, r.URL.RawQuery)
+			}
+			if _, ok := bodies[i][0]["id"]; ok {
+				t.Errorf("%s: request %d body %v includes the key column", name, i, bodies[i][0])
+			}
+		}
+	}
+
+	requests = nil
+	if _, err := client.Table("users").Where("active", "eq", true).FullRows().BulkUpdate(context.Background(), "id", rows); err == nil || len(requests) != 0 {
+		t.Errorf("BulkUpdate() upserting with filters error = %v after %d requests, want an error and none", err, len(requests))
+	}
+
+	if _, err := client.Table("users").BulkUpdate(context.Background(), "id", []map[string]interface{}{{"name": "x"}}); err == nil {
+		t.Error("BulkUpdate() with a row missing the key error = nil, want error")
+	}
+}
+
+func TestOrderByRank(t *testing.T) {
+	q := NewQueryBuilder("articles").OrderByRank("fts", "postgres tuning")
+	if errs := q.Validate(); len(errs) != 0 {
+		t.Fatalf("Validate() = %v", errs)
+	}
+
+	expected := "/articles?fts=wfts.postgres tuning&order=fts_rank.desc.nullslast"
+	if url := q.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
+	}
+
+	unfiltered := NewQueryBuilder("articles").OrderByRank("fts", "")
+	if url := unfiltered.BuildURL(); url != "/articles?order=fts_rank.desc.nullslast" {
+		t.Errorf("BuildURL() without a query = %q", url)
+	}
+}
+
+func TestRawParam(t *testing.T) {
+	q := NewQueryBuilder("users").
+		Where("age", "gte", 18).
+		RawParam("columns", "id,name").
+		RawParam("name", "eq.a&b c")
+
+	expected := "/users?age=gte.18&columns=id%2Cname&name=eq.a%26b+c"
+	if url := q.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %q, want %q", url, expected)
+	}
+
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var users []TestUser
+	err := New(server.URL, "fake-api-key").Table("users").
+		Where("age", "gte", 18).
+		RawParam("name", "eq.a&b c").
+		Get(&users)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if query.Get("name") != "eq.a&b c" || query.Get("age") != "gte.18" {
+		t.Errorf("query = %v, want the raw name parameter alongside age", query)
+	}
+}
+
+func TestGetResult(t *testing.T) {
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Range", "0-1/42")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(`[{"id":1,"name":"John"},{"id":2,"name":"Jane"}]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	result, err := GetResult[TestUser](context.Background(), client.Table("users").Where("age", "gte", 18).Limit(2))
+	if err != nil {
+		t.Fatalf("GetResult() error = %v", err)
+	}
+
+	if len(result.Rows) != 2 || result.Rows[1].Name != "Jane" {
+		t.Errorf("Rows = %+v", result.Rows)
+	}
+	if result.Count != 42 {
+		t.Errorf("Count = %d, want 42", result.Count)
+	}
+	if result.StatusCode != http.StatusPartialContent {
+		t.Errorf("StatusCode = %d, want 206", result.StatusCode)
+	}
+	if result.ContentRange != "0-1/42" {
+		t.Errorf("ContentRange = %q", result.ContentRange)
+	}
+	if prefer != "count=exact" {
+		t.Errorf("Prefer = %q, want count=exact", prefer)
+	}
+}
+
+func TestColumnQuoting(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *QueryBuilder
+		expected string
+	}{
+		{
+			"reserved word",
+			NewQueryBuilder("items").Select("id", "order").Where("order", "gt", 3).Order("order", "desc"),
+			`/items?select=id,"order"&"order"=gt.3&order="order".desc`,
+		},
+		{
+			"mixed case",
+			NewQueryBuilder("users").Select("id", "userName").Where("userName", "eq", "john").Order("createdAt", "asc"),
+			`/users?select=id,"userName"&"userName"=eq.john&order="createdAt".asc`,
+		},
+		{
+			"expressions untouched",
+			NewQueryBuilder("users").Select("tier:metadata->>tier").WhereCast("Price", "numeric", "gt", 10),
+			`/users?select=tier:metadata->>tier&Price::numeric=gt.10`,
+		},
+		{
+			"forced quoting",
+			NewQueryBuilder("users").Select(QuoteColumn("name")),
+			`/users?select="name"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := tt.builder.Validate(); len(errs) != 0 {
+				t.Fatalf("Validate() = %v", errs)
+			}
+			if url := tt.builder.BuildURL(); url != tt.expected {
+				t.Errorf("BuildURL() = %q, want %q", url, tt.expected)
+			}
+		})
+	}
+
+	restricted := NewQueryBuilder("items").RestrictColumns("id", "order").Select("id", "order").Order("order", "asc")
+	if errs := restricted.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() with an allowed quoted column = %v", errs)
+	}
+}
+
+func TestCaptureResult(t *testing.T) {
+	existing := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user TestUser
+		json.NewDecoder(r.Body).Decode(&user)
+
+		w.Header().Set("Content-Type", "application/json")
+		if existing[user.Email] {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			existing[user.Email] = true
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode([]TestUser{user})
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	var inserted WriteResult
+	user := &TestUser{ID: 1, Name: "John", Email: "john@example.com"}
+	if err := client.Table("users").OnConflict("email").CaptureResult(&inserted).Upsert(user); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if inserted.StatusCode != http.StatusCreated || !inserted.Created() {
+		t.Errorf("first upsert status = %d, want 201", inserted.StatusCode)
+	}
+
+	var updated WriteResult
+	user.Name = "Johnny"
+	if err := client.Table("users").OnConflict("email").CaptureResult(&updated).Upsert(user); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if updated.StatusCode != http.StatusOK || updated.Created() {
+		t.Errorf("second upsert status = %d, want 200", updated.StatusCode)
+	}
+
+	var rows []TestUser
+	if err := json.Unmarshal(updated.Rows, &rows); err != nil || len(rows) != 1 || rows[0].Name != "Johnny" {
+		t.Errorf("Rows = %s, want the updated row", updated.Rows)
+	}
+}
@@ -0,0 +1,217 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// QueryRecord is a request written by WithRecorder, for replaying it with ReplayQuery
+type QueryRecord struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// URL is relative to the client's base URL, e.g. /rest/v1/users?id=eq.1
+	URL    string            `json:"url"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+}
+
+// recordedSecrets are the headers left out of records
+var recordedSecrets = map[string]bool{
+	"Apikey": true, "Authorization": true, "Cookie": true, "Proxy-Authorization": true,
+}
+
+// redactedFields are the body fields and query parameters whose values are masked in records
+var redactedFields = map[string]bool{
+	"password": true, "refresh_token": true, "access_token": true, "token": true, "code_verifier": true,
+}
+
+// redacted replaces the values of redactedFields in records
+const redacted = "[REDACTED]"
+
+// WithRecorder writes every request sent by the client to w as a QueryRecord, one JSON
+// object per line, e.g. to attach the queries behind a support ticket. The API key,
+// bearer token and cookies are left out, passwords and tokens are masked in bodies and
+// query strings, and bodies sent to the auth API are not recorded at all. Gzipped bodies
+// are recorded decompressed. Writes to w are serialized, and failures to write don't
+// fail the request.
+func WithRecorder(w io.Writer) ClientOption {
+	return func(c *Client) {
+		var mu sync.Mutex
+		c.httpClient.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+			record, err := newQueryRecord(c.GetBaseURL(), r)
+			if err != nil {
+				return nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			json.NewEncoder(w).Encode(record)
+			return nil
+		})
+	}
+}
+
+// newQueryRecord captures r, with its URL relative to baseURL
+func newQueryRecord(baseURL string, r *resty.Request) (*QueryRecord, error) {
+	target, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	query := target.Query()
+	for name, values := range r.QueryParam {
+		query[name] = append(query[name], values...)
+	}
+	for name := range query {
+		if redactedFields[name] {
+			query.Set(name, redacted)
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	record := &QueryRecord{
+		Time:   time.Now().UTC(),
+		Method: r.Method,
+		URL:    strings.TrimPrefix(target.String(), baseURL),
+		Header: make(map[string]string),
+	}
+
+	for name, values := range r.Header {
+		if !recordedSecrets[http.CanonicalHeaderKey(name)] && name != "Content-Encoding" {
+			record.Header[name] = strings.Join(values, ", ")
+		}
+	}
+
+	// Auth bodies carry credentials, such as passwords and refresh tokens
+	if strings.Contains(target.Path, "/auth/v1/") {
+		return record, nil
+	}
+
+	var raw []byte
+	switch body := r.Body.(type) {
+	case nil:
+		return record, nil
+	case []byte:
+		raw = body
+	case string:
+		raw = []byte(body)
+	case io.Reader:
+		// Reading a stream would consume it before it is sent
+		return record, nil
+	default:
+		if raw, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+		// Resty sends other bodies as JSON, so replays must too
+		if _, ok := record.Header["Content-Type"]; !ok {
+			record.Header["Content-Type"] = "application/json"
+		}
+	}
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(raw) > 0 {
+		if json.Valid(raw) {
+			raw = redactJSON(raw)
+		} else {
+			// Non-JSON bodies are kept as a JSON string
+			raw, _ = json.Marshal(string(raw))
+		}
+		record.Body = raw
+	}
+
+	return record, nil
+}
+
+// redactJSON masks the values of redactedFields at any depth of a JSON body
+func redactJSON(raw []byte) []byte {
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil || !redactValue(data) {
+		return raw
+	}
+
+	redactedRaw, err := json.Marshal(data)
+	if err != nil {
+		return raw
+	}
+	return redactedRaw
+}
+
+// redactValue masks redactedFields in the objects of v, reporting whether any was found
+func redactValue(v interface{}) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if redactedFields[key] {
+				v[key] = redacted
+				found = true
+			} else if redactValue(value) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactValue(item) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// ReplayQuery sends a request captured by WithRecorder again, with this client's base URL
+// and credentials, and decodes the response into result when it is non-nil. Error
+// responses are returned as *APIError, like in Do.
+func (c *Client) ReplayQuery(ctx context.Context, record QueryRecord, result interface{}) error {
+	target := record.URL
+	if strings.HasPrefix(target, "/") {
+		target = c.GetBaseURL() + target
+	}
+
+	req := c.RawRequest().SetContext(ctx).SetHeaders(record.Header)
+	if len(record.Body) > 0 {
+		body := []byte(record.Body)
+		// Non-JSON bodies, such as CSV, were recorded as a JSON string
+		var text string
+		if !strings.Contains(record.Header["Content-Type"], "json") && json.Unmarshal(body, &text) == nil {
+			body = []byte(text)
+		}
+		req.SetBody(body)
+	}
+
+	resp, err := req.Execute(record.Method, target)
+	if err != nil {
+		return fmt.Errorf("replay %s %s: %w", record.Method, record.URL, err)
+	}
+
+	if resp.IsError() {
+		return newAPIError(resp)
+	}
+
+	if result != nil && len(resp.Body()) > 0 {
+		return json.Unmarshal(resp.Body(), result)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func TestRecorderReplay(t *testing.T) {
+	type request struct {
+		method, uri, prefer, auth, body string
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.RequestURI(), r.Header.Get("Prefer"), r.Header.Get("Authorization"), string(body)})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":1,"name":"Ann"}]`))
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	client := New(server.URL, "secret-key", WithRecorder(&log))
+
+	var users []map[string]interface{}
+	if err := client.Table("users").Select("id", "name").Where("id", "eq", 1).Get(&users); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	err := client.Table("users").Header("Prefer", "return=minimal").Insert(map[string]interface{}{"name": "Ann"})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	if strings.Contains(log.String(), "secret-key") {
+		t.Errorf("records contain the API key: %s", log.String())
+	}
+
+	var records []QueryRecord
+	decoder := json.NewDecoder(&log)
+	for decoder.More() {
+		var record QueryRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("decoding record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	if records[0].Method != http.MethodGet || records[0].URL != "/rest/v1/users?id=eq.1&select=id%2Cname" {
+		t.Errorf("read record = %s %s", records[0].Method, records[0].URL)
+	}
+	if string(records[1].Body) != `{"name":"Ann"}` || records[1].Header["Prefer"] != "return=minimal" {
+		t.Errorf("insert record = %+v", records[1])
+	}
+
+	// Replay against another server with other credentials
+	replayer := New(server.URL, "support-key")
+	var replayed []map[string]interface{}
+	if err := replayer.ReplayQuery(context.Background(), records[0], &replayed); err != nil {
+		t.Fatalf("ReplayQuery() error = %v", err)
+	}
+	if err := replayer.ReplayQuery(context.Background(), records[1], nil); err != nil {
+		t.Fatalf("ReplayQuery() error = %v", err)
+	}
+
+	if len(replayed) != 1 || replayed[0]["name"] != "Ann" {
+		t.Errorf("replayed rows = %v", replayed)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("requests = %d, want 4", len(requests))
+	}
+	for i := 0; i < 2; i++ {
+		original, replay := requests[i], requests[i+2]
+		if replay.method != original.method || replay.uri != original.uri || replay.prefer != original.prefer || replay.body != original.body {
+			t.Errorf("replay %d = %+v, want %+v", i, replay, original)
+		}
+		if replay.auth != "Bearer support-key" {
+			t.Errorf("replay %d Authorization = %q, want the replaying client's key", i, replay.auth)
+		}
+	}
+}
+
+func TestRecorderRedactsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	hooked := false
+	client := New(server.URL, "secret-key", WithRecorder(&log))
+	client.httpClient.SetPreRequestHook(func(_ *resty.Client, _ *http.Request) error {
+		hooked = true
+		return nil
+	})
+
+	client.Auth().SignInWithPassword(context.Background(), SignInRequest{Email: "a@b.c", Password: "hunter2"})
+	err := client.Do(context.Background(), http.MethodPost, "/rest/v1/rpc/rotate?token=abc", map[string]interface{}{
+		"user":     map[string]interface{}{"password": "hunter2"},
+		"sessions": []interface{}{map[string]interface{}{"refresh_token": "r-1", "code_verifier": "v-1"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	if !hooked {
+		t.Error("WithRecorder replaced the client's pre-request hook")
+	}
+	for _, secret := range []string{"hunter2", "a@b.c", "abc", "r-1", "v-1"} {
+		if strings.Contains(log.String(), secret) {
+			t.Errorf("records contain %q: %s", secret, log.String())
+		}
+	}
+
+	var records []QueryRecord
+	decoder := json.NewDecoder(&log)
+	for decoder.More() {
+		var record QueryRecord
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("decoding record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	if len(records[0].Body) != 0 || !strings.HasPrefix(records[0].URL, "/auth/v1/token") {
+		t.Errorf("auth record = %s %s", records[0].URL, records[0].Body)
+	}
+	if records[1].URL != "/rest/v1/rpc/rotate?token=%5BREDACTED%5D" || !strings.Contains(string(records[1].Body), redacted) {
+		t.Errorf("rpc record = %s %s", records[1].URL, records[1].Body)
+	}
+}
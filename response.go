@@ -1,6 +1,10 @@
 package supabaseorm
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+
 	"github.com/go-resty/resty/v2"
 )
 
@@ -43,9 +47,104 @@ func (r *Response) IsError() bool {
 	return r.StatusCode >= 400
 }
 
-// GetContentRange parses the Content-Range header
+// GetContentRange parses the Content-Range header (e.g., "0-9/42")
+// into the first and last row returned and the total count
 func (r *Response) GetContentRange() (int, int, int) {
-	// Parse Content-Range header (e.g., "0-9/42")
-	// This is a placeholder - in a real implementation, you'd parse the header
-	return 0, 0, 0
+	return ParseContentRange(r.Headers["Content-Range"])
+}
+
+// EmbeddedCount decodes an embedded aggregate such as posts(count),
+// which PostgREST returns as [{"count":n}], into a plain integer
+type EmbeddedCount int64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *EmbeddedCount) UnmarshalJSON(data []byte) error {
+	var rows []struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(data, &rows); err == nil {
+		if len(rows) > 0 {
+			*c = EmbeddedCount(rows[0].Count)
+		}
+		return nil
+	}
+
+	// Embedded to-one relationships return a single object
+	var row struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.Unmarshal(data, &row); err != nil {
+		return err
+	}
+	*c = EmbeddedCount(row.Count)
+	return nil
+}
+
+// WriteResult holds the status and body of a write, see CaptureResult. PostgREST
+// answers 201 Created when an insert or upsert created rows, 200 OK when an update,
+// delete or merging upsert returned rows, and 204 No Content without return=representation.
+type WriteResult struct {
+	StatusCode int
+	// Rows is the returned representation, empty with 204 No Content
+	Rows json.RawMessage
+}
+
+// Created reports whether the write answered 201 Created
+func (r *WriteResult) Created() bool {
+	return r.StatusCode == http.StatusCreated
+}
+
+// CaptureResult records the status and body of the query's response in r, e.g. to
+// tell whether an upsert inserted or updated:
+//
+//	var res WriteResult
+//	err := client.Table("users").OnConflict("email").CaptureResult(&res).Upsert(&user)
+//	inserted := res.Created()
+func (q *QueryBuilder) CaptureResult(r *WriteResult) *QueryBuilder {
+	q.writeResult = r
+	return q
+}
+
+// Result holds the rows of a read along with the response metadata, see GetResult
+type Result[T any] struct {
+	Rows []T
+	// Count is the total number of matching rows, from Content-Range
+	Count int64
+	// StatusCode is 200, or 206 when the rows are a partial range of the total
+	StatusCode int
+	// ContentRange is the raw Content-Range header, e.g. 0-9/42
+	ContentRange string
+}
+
+// GetResult runs the query and returns its rows with the total count and status in
+// one value, e.g. GetResult[User](ctx, client.Table("users").Limit(10)). The count
+// is exact unless another mode was set with CountMode, and ErrCountUnavailable is
+// returned when the response carries no total.
+func GetResult[T any](ctx context.Context, q *QueryBuilder) (*Result[T], error) {
+	q.method = http.MethodGet
+	q.ctx = ctx
+	if q.countQuery == "" {
+		q.Count()
+	}
+
+	resp, err := q.send(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result[T]{
+		Rows:         []T{},
+		StatusCode:   resp.StatusCode(),
+		ContentRange: resp.Header().Get("Content-Range"),
+	}
+	if err := q.decode(resp, &result.Rows); err != nil {
+		return nil, err
+	}
+
+	count, err := contentRangeTotal(result.ContentRange)
+	if err != nil {
+		return nil, err
+	}
+	result.Count = count
+	return result, nil
 }
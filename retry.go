@@ -0,0 +1,78 @@
+package supabaseorm
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// IdempotencyKeyHeader is the header set by IdempotencyKey
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// retryWritesKey marks the context of a write that may be retried, see RetryWrites
+type retryWritesKey struct{}
+
+// WithRetry retries failed requests up to maxRetries times, waiting from wait up to
+// eight times wait between attempts. A request is retried after a network error or a
+// 429, 502, 503 or 504 response. Reads are always retried, but a POST, PATCH or DELETE
+// only when it carries an IdempotencyKey or opts in with RetryWrites, since a write
+// that timed out may have been applied and retrying it could duplicate rows.
+func WithRetry(maxRetries int, wait time.Duration) ClientOption {
+	return func(c *Client) {
+		c.httpClient.
+			SetRetryCount(maxRetries).
+			SetRetryWaitTime(wait).
+			SetRetryMaxWaitTime(8 * wait).
+			AddRetryCondition(shouldRetry)
+	}
+}
+
+// IdempotencyKey sets the Idempotency-Key header, letting WithRetry retry the write
+// when a gateway in front of PostgREST uses the key to drop duplicate requests
+func (q *QueryBuilder) IdempotencyKey(key string) *QueryBuilder {
+	return q.Header(IdempotencyKeyHeader, key)
+}
+
+// RetryWrites lets WithRetry retry the query even though it is a write, for writes
+// that are safe to repeat, such as an upsert or an update setting fixed values
+func (q *QueryBuilder) RetryWrites() *QueryBuilder {
+	q.retryWrites = true
+	return q
+}
+
+// shouldRetry decides whether WithRetry sends a failed request again
+func shouldRetry(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil {
+		return false
+	}
+
+	if err == nil {
+		switch resp.StatusCode() {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		default:
+			return false
+		}
+	}
+
+	req := resp.Request
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	if req.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+	retryWrites, _ := req.Context().Value(retryWritesKey{}).(bool)
+	return retryWrites
+}
+
+// retryContext marks ctx so that WithRetry may retry the write sent with it
+func retryContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, retryWritesKey{}, true)
+}
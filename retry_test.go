@@ -0,0 +1,64 @@
+package supabaseorm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.Path
+		attempts[key]++
+		if attempts[key] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key", WithRetry(2, time.Millisecond))
+
+	t.Run("read is retried", func(t *testing.T) {
+		var users []TestUser
+		if err := client.Table("users").Get(&users); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if n := attempts["GET /rest/v1/users"]; n != 2 {
+			t.Errorf("GET attempts = %d, want 2", n)
+		}
+	})
+
+	t.Run("write is not retried by default", func(t *testing.T) {
+		if err := client.Table("orders").Insert(map[string]interface{}{"amount": 10}); err == nil {
+			t.Error("Insert() error = nil, want the 503")
+		}
+		if n := attempts["POST /rest/v1/orders"]; n != 1 {
+			t.Errorf("POST attempts = %d, want 1", n)
+		}
+	})
+
+	t.Run("write with idempotency key is retried", func(t *testing.T) {
+		err := client.Table("payments").IdempotencyKey("pay-42").Insert(map[string]interface{}{"amount": 10})
+		if err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+		if n := attempts["POST /rest/v1/payments"]; n != 2 {
+			t.Errorf("POST attempts = %d, want 2", n)
+		}
+	})
+
+	t.Run("write opted in is retried", func(t *testing.T) {
+		err := client.Table("settings").Where("id", "eq", 1).RetryWrites().Update(map[string]interface{}{"theme": "dark"})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if n := attempts["PATCH /rest/v1/settings"]; n != 2 {
+			t.Errorf("PATCH attempts = %d, want 2", n)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ColumnInfo describes a column as reported by the PostgREST OpenAPI description
+type ColumnInfo struct {
+	Name string
+	// Type is the json type, e.g. integer or string
+	Type string
+	// Format is the PostgreSQL type, e.g. bigint or timestamp with time zone
+	Format string
+	// Required is set for columns that are not null and have no default
+	Required bool
+}
+
+// TableSchema describes the columns of a table or view, in their table order
+type TableSchema struct {
+	Name    string
+	Columns []ColumnInfo
+}
+
+// ColumnNames returns the names of the table's columns
+func (s *TableSchema) ColumnNames() []string {
+	names := make([]string, len(s.Columns))
+	for i, column := range s.Columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// schemaCache holds the tables described by DescribeTable, keyed by schema and table.
+// It is shared by the copies of a client returned by Schema and the With methods.
+type schemaCache struct {
+	mu     sync.RWMutex
+	tables map[string]*TableSchema
+}
+
+func (s *schemaCache) get(key string) (*TableSchema, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	table, ok := s.tables[key]
+	return table, ok
+}
+
+func (s *schemaCache) set(key string, table *TableSchema) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tables == nil {
+		s.tables = make(map[string]*TableSchema)
+	}
+	s.tables[key] = table
+}
+
+// schemaKey identifies a table within the client's schema
+func (c *Client) schemaKey(table string) string {
+	return c.schema + "." + table
+}
+
+// DescribeTable fetches the columns of table from the PostgREST OpenAPI description
+// and caches them on the client for SelectExcept. Call it again to refresh the cache
+// after a migration.
+func (c *Client) DescribeTable(ctx context.Context, table string) (*TableSchema, error) {
+	req := c.RawRequest().SetContext(ctx).SetHeader("Accept", "application/openapi+json")
+	if c.schema != "" {
+		req.SetHeader("Accept-Profile", c.schema)
+	}
+
+	resp, err := req.Get(fmt.Sprintf("%s/rest/v1/", c.GetBaseURL()))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, newAPIError(resp)
+	}
+
+	var spec struct {
+		Definitions map[string]struct {
+			Required   []string        `json:"required"`
+			Properties json.RawMessage `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(resp.Body(), &spec); err != nil {
+		return nil, fmt.Errorf("decode OpenAPI description: %w", err)
+	}
+
+	definition, ok := spec.Definitions[table]
+	if !ok {
+		return nil, fmt.Errorf("table %s not found in the OpenAPI description", table)
+	}
+
+	// Properties are listed in table order, which a map would lose
+	names, err := objectKeys(definition.Properties)
+	if err != nil {
+		return nil, fmt.Errorf("decode columns of %s: %w", table, err)
+	}
+
+	var properties map[string]struct {
+		Type   string `json:"type"`
+		Format string `json:"format"`
+	}
+	if err := json.Unmarshal(definition.Properties, &properties); err != nil {
+		return nil, fmt.Errorf("decode columns of %s: %w", table, err)
+	}
+
+	required := make(map[string]bool, len(definition.Required))
+	for _, name := range definition.Required {
+		required[name] = true
+	}
+
+	schema := &TableSchema{Name: table, Columns: make([]ColumnInfo, 0, len(names))}
+	for _, name := range names {
+		schema.Columns = append(schema.Columns, ColumnInfo{
+			Name:     name,
+			Type:     properties[name].Type,
+			Format:   properties[name].Format,
+			Required: required[name],
+		})
+	}
+
+	if c.tableSchemas != nil {
+		c.tableSchemas.set(c.schemaKey(table), schema)
+	}
+
+	return schema, nil
+}
+
+// cachedTableSchema returns the columns of table loaded by DescribeTable
+func (c *Client) cachedTableSchema(table string) (*TableSchema, bool) {
+	if c.tableSchemas == nil {
+		return nil, false
+	}
+	return c.tableSchemas.get(c.schemaKey(table))
+}
+
+// objectKeys returns the keys of a json object in document order
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("expected a json object")
+	}
+
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// SelectExcept selects every column of the table except the given ones. PostgREST has
+// no column exclusion, so the select is built from the columns cached by DescribeTable,
+// which must have been called for the table; otherwise the query fails validation.
+func (q *QueryBuilder) SelectExcept(columns ...string) *QueryBuilder {
+	if q.client == nil {
+		q.errs = append(q.errs, fmt.Errorf("SelectExcept on %s requires a client", q.table))
+		return q
+	}
+
+	schema, ok := q.client.cachedTableSchema(q.table)
+	if !ok {
+		q.errs = append(q.errs, fmt.Errorf("SelectExcept on %s: schema not loaded, call DescribeTable first", q.table))
+		return q
+	}
+
+	excluded := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		excluded[column] = true
+	}
+
+	var selected []string
+	for _, column := range schema.Columns {
+		if excluded[column.Name] {
+			delete(excluded, column.Name)
+			continue
+		}
+		selected = append(selected, column.Name)
+	}
+
+	for _, column := range columns {
+		if excluded[column] {
+			q.errs = append(q.errs, fmt.Errorf("SelectExcept on %s: unknown column %q", q.table, column))
+		}
+	}
+
+	if len(selected) == 0 {
+		q.errs = append(q.errs, fmt.Errorf("SelectExcept on %s excludes every column", q.table))
+		return q
+	}
+
+	q.selectQuery = strings.Join(selected, ",")
+	return q
+}
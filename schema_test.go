@@ -0,0 +1,75 @@
+package supabaseorm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testOpenAPI = `{
+	"swagger": "2.0",
+	"definitions": {
+		"users": {
+			"required": ["id", "email"],
+			"properties": {
+				"id": {"format": "bigint", "type": "integer"},
+				"email": {"format": "text", "type": "string"},
+				"password_hash": {"format": "text", "type": "string"},
+				"name": {"format": "text", "type": "string"},
+				"created_at": {"format": "timestamp with time zone", "type": "string"}
+			},
+			"type": "object"
+		}
+	}
+}`
+
+func TestSelectExcept(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/v1/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/openapi+json")
+		w.Write([]byte(testOpenAPI))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	if errs := client.Table("users").SelectExcept("password_hash").Validate(); len(errs) == 0 {
+		t.Error("SelectExcept() before DescribeTable did not report an error")
+	}
+
+	schema, err := client.DescribeTable(context.Background(), "users")
+	if err != nil {
+		t.Fatalf("DescribeTable() error = %v", err)
+	}
+
+	wantColumns := []string{"id", "email", "password_hash", "name", "created_at"}
+	if !reflect.DeepEqual(schema.ColumnNames(), wantColumns) {
+		t.Errorf("ColumnNames() = %v, want %v", schema.ColumnNames(), wantColumns)
+	}
+	if !schema.Columns[0].Required || schema.Columns[0].Format != "bigint" {
+		t.Errorf("Columns[0] = %+v, want required bigint", schema.Columns[0])
+	}
+
+	q := client.Table("users").SelectExcept("password_hash", "created_at")
+	if errs := q.Validate(); len(errs) > 0 {
+		t.Fatalf("Validate() = %v", errs)
+	}
+	if q.selectQuery != "id,email,name" {
+		t.Errorf("selectQuery = %q, want id,email,name", q.selectQuery)
+	}
+
+	errs := client.Table("users").SelectExcept("passwd").Validate()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "passwd") {
+		t.Errorf("Validate() with an unknown column = %v", errs)
+	}
+
+	if _, err := client.DescribeTable(context.Background(), "missing"); err == nil {
+		t.Error("DescribeTable() for an unknown table error = nil, want error")
+	}
+}
@@ -0,0 +1,126 @@
+package supabaseorm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// aggregateFunctions are the aggregates PostgREST accepts in a select
+var aggregateFunctions = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+}
+
+// SelectBuilder assembles a select from columns, aliases, embeds, spreads and aggregates,
+// e.g.
+//
+//	q.SelectB().
+//		Column("id").
+//		Column("full_name").As("name").
+//		Embed("posts", func(s *SelectBuilder) {
+//			s.Column("title").Embed("comments", func(s *SelectBuilder) { s.Column("body") })
+//		}).
+//		End()
+//
+// selects id,name:full_name,posts(title,comments(body)). Column names are quoted like
+// in Select, and invalid names and functions are reported by the query's Validate.
+type SelectBuilder struct {
+	query *QueryBuilder
+	items []string
+}
+
+// SelectB returns a builder for the query's select. End replaces any previous Select
+// with the assembled columns.
+func (q *QueryBuilder) SelectB() *SelectBuilder {
+	return &SelectBuilder{query: q}
+}
+
+// Column adds a column, or * for all columns
+func (s *SelectBuilder) Column(name string) *SelectBuilder {
+	if name != "*" && !identifierPattern.MatchString(name) {
+		return s.fail(fmt.Errorf("invalid select column %q", name))
+	}
+	s.items = append(s.items, quoteColumn(name))
+	return s
+}
+
+// As renames the item added last, e.g. Column("full_name").As("name") emits name:full_name
+func (s *SelectBuilder) As(alias string) *SelectBuilder {
+	if !identifierPattern.MatchString(alias) {
+		return s.fail(fmt.Errorf("invalid alias %q", alias))
+	}
+	if len(s.items) == 0 || strings.HasPrefix(s.items[len(s.items)-1], "...") {
+		return s.fail(fmt.Errorf("alias %q has nothing to rename", alias))
+	}
+	s.items[len(s.items)-1] = alias + ":" + s.items[len(s.items)-1]
+	return s
+}
+
+// Embed adds an embedded resource whose columns are set by fn, e.g. posts(id,title).
+// A nil fn, or one that adds nothing, selects all of the resource's columns.
+func (s *SelectBuilder) Embed(table string, fn func(*SelectBuilder)) *SelectBuilder {
+	return s.resource("", table, fn)
+}
+
+// Spread adds the columns of a to-one embedded resource to the parent row instead of
+// nesting them, e.g. ...profiles(bio) returns bio next to the user's columns
+func (s *SelectBuilder) Spread(table string, fn func(*SelectBuilder)) *SelectBuilder {
+	return s.resource("...", table, fn)
+}
+
+// Aggregate adds an aggregate function over column, e.g. Aggregate("amount", "sum")
+// emits amount.sum(). An empty column counts the rows with count(). Aggregates require
+// PostgREST 12 and group by the other selected columns.
+func (s *SelectBuilder) Aggregate(column, function string) *SelectBuilder {
+	if !aggregateFunctions[function] {
+		return s.fail(fmt.Errorf("invalid aggregate function %q", function))
+	}
+	if column == "" {
+		if function != "count" {
+			return s.fail(fmt.Errorf("aggregate %s needs a column", function))
+		}
+		s.items = append(s.items, "count()")
+		return s
+	}
+	if !identifierPattern.MatchString(column) {
+		return s.fail(fmt.Errorf("invalid aggregate column %q", column))
+	}
+	s.items = append(s.items, quoteColumn(column)+"."+function+"()")
+	return s
+}
+
+// Build returns the assembled select, e.g. id,name:full_name,posts(title), or * when
+// nothing was added
+func (s *SelectBuilder) Build() string {
+	if len(s.items) == 0 {
+		return "*"
+	}
+	return strings.Join(s.items, ",")
+}
+
+// End sets the assembled select on the query and returns it
+func (s *SelectBuilder) End() *QueryBuilder {
+	s.query.selectQuery = s.Build()
+	return s.query
+}
+
+// resource adds an embedded or spread resource with the columns set by fn
+func (s *SelectBuilder) resource(prefix, table string, fn func(*SelectBuilder)) *SelectBuilder {
+	if !identifierPattern.MatchString(table) {
+		return s.fail(fmt.Errorf("invalid embedded table %q", table))
+	}
+
+	nested := &SelectBuilder{query: s.query}
+	if fn != nil {
+		fn(nested)
+	}
+	s.items = append(s.items, fmt.Sprintf("%s%s(%s)", prefix, table, nested.Build()))
+	return s
+}
+
+// fail records err on the query
+func (s *SelectBuilder) fail(err error) *SelectBuilder {
+	if s.query != nil {
+		s.query.errs = append(s.query.errs, err)
+	}
+	return s
+}
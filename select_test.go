@@ -0,0 +1,65 @@
+package supabaseorm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	qb := NewQueryBuilder("users").SelectB().
+		Column("id").
+		Column("full_name").As("name").
+		Embed("posts", func(s *SelectBuilder) {
+			s.Column("title").
+				Embed("comments", func(s *SelectBuilder) {
+					s.Column("body").Column("created_at")
+				})
+		}).
+		Embed("users", nil).As("manager").
+		End()
+
+	expected := "/users?select=id,name:full_name,posts(title,comments(body,created_at)),manager:users(*)"
+	if url := qb.BuildURL(); url != expected {
+		t.Errorf("BuildURL() = %v, want %v", url, expected)
+	}
+	if errs := qb.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors", errs)
+	}
+
+	tests := []struct {
+		name     string
+		builder  *SelectBuilder
+		expected string
+	}{
+		{"empty", NewQueryBuilder("users").SelectB(), "*"},
+		{"quoted", NewQueryBuilder("users").SelectB().Column("order").Column("createdAt"), `"order","createdAt"`},
+		{"spread", NewQueryBuilder("users").SelectB().Column("id").Spread("profiles", func(s *SelectBuilder) { s.Column("bio") }), "id,...profiles(bio)"},
+		{"aggregates", NewQueryBuilder("orders").SelectB().Column("status").Aggregate("amount", "sum").As("total").Aggregate("", "count"), "status,total:amount.sum(),count()"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.builder.Build(); got != tt.expected {
+				t.Errorf("Build() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	invalid := []*QueryBuilder{
+		NewQueryBuilder("users").SelectB().Column("id;drop").End(),
+		NewQueryBuilder("users").SelectB().As("name").End(),
+		NewQueryBuilder("users").SelectB().Aggregate("amount", "median").End(),
+		NewQueryBuilder("users").SelectB().Embed("posts", func(s *SelectBuilder) { s.Column("bad name") }).End(),
+	}
+	for i, q := range invalid {
+		if errs := q.Validate(); len(errs) != 1 {
+			t.Errorf("invalid[%d].Validate() = %v, want one error", i, errs)
+		}
+	}
+
+	// Aggregates are gated on the server version like in Select
+	old := New("http://localhost:54321", "fake-api-key", WithAssumeServerVersion("11.2.0"))
+	errs := old.Table("orders").SelectB().Aggregate("amount", "sum").End().Validate()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrUnsupportedFeature) {
+		t.Errorf("Validate() on PostgREST 11 = %v, want ErrUnsupportedFeature", errs)
+	}
+}
@@ -0,0 +1,191 @@
+package supabaseorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ServerInfo describes the PostgREST server behind the client
+type ServerInfo struct {
+	// Version is the version as reported by the server, e.g. 12.0.2
+	Version string
+	Major   int
+	Minor   int
+	Patch   int
+}
+
+// AtLeast reports whether the server version is major.minor or newer
+func (s *ServerInfo) AtLeast(major, minor int) bool {
+	if s.Major != major {
+		return s.Major > major
+	}
+	return s.Minor >= minor
+}
+
+// WithAssumeServerVersion makes the client assume the given PostgREST version, e.g.
+// "12.0.2", instead of asking the server, for offline use and mock servers. An invalid
+// version is ignored.
+func WithAssumeServerVersion(version string) ClientOption {
+	return func(c *Client) {
+		info, err := parseServerVersion(version)
+		if err != nil {
+			return
+		}
+		c.serverInfo = &serverInfoCache{info: info, assumed: true}
+	}
+}
+
+// serverInfoCache holds the server info once it has been fetched.
+// It is shared by the copies of a client returned by Schema and the With methods.
+type serverInfoCache struct {
+	mu   sync.Mutex
+	info *ServerInfo
+	// assumed is set for a version given with WithAssumeServerVersion, which holds for
+	// any URL
+	assumed bool
+}
+
+// ServerInfo returns the PostgREST version, read from the Server header of the root
+// endpoint or, when a proxy replaces that header, from the OpenAPI description. The
+// result is cached on the client, so it is fetched once.
+func (c *Client) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	if info := c.cachedServerInfo(); info != nil {
+		return info, nil
+	}
+
+	resp, err := c.RawRequest().
+		SetContext(ctx).
+		SetHeader("Accept", "application/openapi+json").
+		Get(fmt.Sprintf("%s/rest/v1/", c.GetBaseURL()))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, newAPIError(resp)
+	}
+
+	// The Server header reads e.g. postgrest/12.0.2
+	version := ""
+	if name, v, ok := strings.Cut(resp.Header().Get("Server"), "/"); ok && strings.EqualFold(name, "postgrest") {
+		version = v
+	}
+
+	if version == "" {
+		// info.version reads e.g. 12.0.2 (a4e00ff)
+		var spec struct {
+			Info struct {
+				Version string `json:"version"`
+			} `json:"info"`
+		}
+		if err := json.Unmarshal(resp.Body(), &spec); err == nil {
+			version, _, _ = strings.Cut(spec.Info.Version, " ")
+		}
+	}
+
+	info, err := parseServerVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.serverInfo != nil {
+		c.serverInfo.mu.Lock()
+		c.serverInfo.info = info
+		c.serverInfo.mu.Unlock()
+	}
+
+	return info, nil
+}
+
+// cachedServerInfo returns the server info fetched by ServerInfo, or nil
+func (c *Client) cachedServerInfo() *ServerInfo {
+	if c.serverInfo == nil {
+		return nil
+	}
+	c.serverInfo.mu.Lock()
+	defer c.serverInfo.mu.Unlock()
+	return c.serverInfo.info
+}
+
+// parseServerVersion parses a major.minor.patch version; missing parts are zero
+func parseServerVersion(version string) (*ServerInfo, error) {
+	if version == "" {
+		return nil, fmt.Errorf("server did not report a PostgREST version")
+	}
+
+	info := &ServerInfo{Version: version}
+	parts := strings.SplitN(version, ".", 3)
+	numbers := []*int{&info.Major, &info.Minor, &info.Patch}
+	for i, part := range parts {
+		// Drop pre-release suffixes such as 12.0.0-rc1
+		part, _, _ = strings.Cut(part, "-")
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PostgREST version %q", version)
+		}
+		*numbers[i] = n
+	}
+
+	return info, nil
+}
+
+// serverFeature is a PostgREST capability and the version that introduced it
+type serverFeature struct {
+	name  string
+	major int
+	minor int
+}
+
+var (
+	featureStrictHandling = serverFeature{"Prefer: handling=strict", 11, 1}
+	featureMaxAffected    = serverFeature{"Prefer: max-affected", 12, 0}
+	featureAggregates     = serverFeature{"aggregate functions", 12, 0}
+	featureEmbedNull      = serverFeature{"null filters on embedded resources", 11, 0}
+)
+
+// aggregatePattern matches aggregate functions in a select, e.g. amount.sum() or count()
+var aggregatePattern = regexp.MustCompile(`\b(count|sum|avg|min|max)\(\)`)
+
+// validateFeatures reports the features used by the query that the server lacks.
+// Nothing is checked until the version is known, from ServerInfo or WithAssumeServerVersion.
+func (q *QueryBuilder) validateFeatures() []error {
+	if q.client == nil {
+		return nil
+	}
+	info := q.client.cachedServerInfo()
+	if info == nil {
+		return nil
+	}
+
+	var used []serverFeature
+	prefer := q.headers["Prefer"]
+	switch {
+	case strings.Contains(prefer, "max-affected="):
+		used = append(used, featureMaxAffected)
+	case strings.Contains(prefer, "handling=strict"):
+		used = append(used, featureStrictHandling)
+	}
+	if aggregatePattern.MatchString(q.buildSelect()) {
+		used = append(used, featureAggregates)
+	}
+	for _, embed := range q.embedBuilders {
+		if embed.absent {
+			used = append(used, featureEmbedNull)
+			break
+		}
+	}
+
+	var errs []error
+	for _, feature := range used {
+		if !info.AtLeast(feature.major, feature.minor) {
+			errs = append(errs, fmt.Errorf("%w: %s requires PostgREST %d.%d, server is %s",
+				ErrUnsupportedFeature, feature.name, feature.major, feature.minor, info.Version))
+		}
+	}
+	return errs
+}
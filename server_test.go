@@ -0,0 +1,137 @@
+package supabaseorm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerInfo(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Server", "postgrest/12.0.2")
+		w.Header().Set("Content-Type", "application/openapi+json")
+		w.Write([]byte(`{"swagger":"2.0","info":{"version":"11.2.0 (a4e00ff)"}}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	info, err := client.ServerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerInfo() error = %v", err)
+	}
+	if info.Version != "12.0.2" || info.Major != 12 || info.Minor != 0 || info.Patch != 2 {
+		t.Errorf("ServerInfo() = %+v, want 12.0.2", info)
+	}
+	if !info.AtLeast(11, 2) || !info.AtLeast(12, 0) || info.AtLeast(12, 1) {
+		t.Errorf("AtLeast() comparisons wrong for %s", info.Version)
+	}
+
+	if _, err := client.Schema("analytics").ServerInfo(context.Background()); err != nil {
+		t.Fatalf("ServerInfo() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1 with the version cached", requests)
+	}
+}
+
+func TestServerInfoAfterSetBaseURL(t *testing.T) {
+	newServer := func(version string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", "postgrest/"+version)
+			w.Write([]byte(`{"swagger":"2.0"}`))
+		}))
+	}
+	oldServer, newerServer := newServer("11.2.0"), newServer("12.0.2")
+	defer oldServer.Close()
+	defer newerServer.Close()
+
+	client := New(oldServer.URL, "fake-api-key")
+	clone := client.Schema("analytics")
+	if _, err := client.ServerInfo(context.Background()); err != nil {
+		t.Fatalf("ServerInfo() error = %v", err)
+	}
+
+	if err := client.SetBaseURL(newerServer.URL); err != nil {
+		t.Fatalf("SetBaseURL() error = %v", err)
+	}
+	info, err := client.ServerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerInfo() error = %v", err)
+	}
+	if info.Version != "12.0.2" {
+		t.Errorf("ServerInfo() after SetBaseURL = %s, want 12.0.2", info.Version)
+	}
+
+	// The copy still points at the old server and keeps its version
+	if info, err := clone.ServerInfo(context.Background()); err != nil || info.Version != "11.2.0" {
+		t.Errorf("ServerInfo() of the copy = %v, %v, want 11.2.0", info, err)
+	}
+}
+
+func TestServerInfoFromOpenAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "cloudflare")
+		w.Write([]byte(`{"swagger":"2.0","info":{"version":"11.2.0 (a4e00ff)"}}`))
+	}))
+	defer server.Close()
+
+	info, err := New(server.URL, "fake-api-key").ServerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("ServerInfo() error = %v", err)
+	}
+	if info.Version != "11.2.0" || info.Major != 11 || info.Minor != 2 {
+		t.Errorf("ServerInfo() = %+v, want 11.2.0", info)
+	}
+}
+
+func TestFeatureGating(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		build   func(c *Client) *QueryBuilder
+		wantErr bool
+	}{
+		{"max-affected on 12", "12.0.2", func(c *Client) *QueryBuilder {
+			return c.Table("users").Where("id", "eq", 1).MaxAffected(1)
+		}, false},
+		{"max-affected on 11", "11.2.0", func(c *Client) *QueryBuilder {
+			return c.Table("users").Where("id", "eq", 1).MaxAffected(1)
+		}, true},
+		{"aggregate on 12", "12.2.3", func(c *Client) *QueryBuilder {
+			return c.Table("orders").Select("amount.sum()")
+		}, false},
+		{"aggregate on 11", "11.2.0", func(c *Client) *QueryBuilder {
+			return c.Table("orders").Select("total:amount.sum()")
+		}, true},
+		{"embedded count on 11", "11.2.0", func(c *Client) *QueryBuilder {
+			return c.Table("users").SelectCount("posts")
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New("http://localhost:54321", "fake-api-key", WithAssumeServerVersion(tt.version))
+			errs := tt.build(client).Validate()
+			if tt.wantErr {
+				if len(errs) != 1 || !errors.Is(errs[0], ErrUnsupportedFeature) {
+					t.Errorf("Validate() = %v, want ErrUnsupportedFeature", errs)
+				}
+				return
+			}
+			if len(errs) != 0 {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+
+	// Without a known version nothing is gated
+	unknown := New("http://localhost:54321", "fake-api-key")
+	if errs := unknown.Table("users").Where("id", "eq", 1).MaxAffected(1).Validate(); len(errs) != 0 {
+		t.Errorf("Validate() without a version = %v, want no errors", errs)
+	}
+}
@@ -0,0 +1,394 @@
+package supabaseorm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// StorageClient provides methods for Supabase Storage
+type StorageClient struct {
+	client *Client
+}
+
+// FileObject represents an object or folder in a storage bucket
+type FileObject struct {
+	Name           string       `json:"name"`
+	ID             string       `json:"id"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	LastAccessedAt time.Time    `json:"last_accessed_at"`
+	Metadata       FileMetadata `json:"metadata"`
+}
+
+// FileMetadata holds the metadata of a stored object
+type FileMetadata struct {
+	Size         int64  `json:"size"`
+	MimeType     string `json:"mimetype"`
+	ETag         string `json:"eTag"`
+	CacheControl string `json:"cacheControl"`
+}
+
+// SortBy sets the ordering of a storage listing
+type SortBy struct {
+	Column string `json:"column"`
+	Order  string `json:"order"`
+}
+
+// ListOptions configures a storage listing
+type ListOptions struct {
+	Limit  int
+	Offset int
+	SortBy SortBy
+	Search string
+}
+
+// listRequest represents the request body for listing objects
+type listRequest struct {
+	Prefix string  `json:"prefix"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+	SortBy *SortBy `json:"sortBy,omitempty"`
+	Search string  `json:"search,omitempty"`
+}
+
+// defaultListLimit is the page size used when ListOptions.Limit is not set
+const defaultListLimit = 100
+
+// NewStorageClient creates a new StorageClient instance
+func NewStorageClient(client *Client) *StorageClient {
+	return &StorageClient{
+		client: client,
+	}
+}
+
+// Storage returns the StorageClient for file storage operations
+func (c *Client) Storage() *StorageClient {
+	return NewStorageClient(c)
+}
+
+// List lists the objects and folders under prefix in a bucket, one page at a time.
+// Page through large buckets by increasing opts.Offset by opts.Limit until fewer
+// than opts.Limit objects are returned.
+func (s *StorageClient) List(ctx context.Context, bucket, prefix string, opts ListOptions) ([]FileObject, error) {
+	endpoint := fmt.Sprintf("%s/storage/v1/object/list/%s", s.client.GetBaseURL(), url.PathEscape(bucket))
+
+	body := listRequest{
+		Prefix: prefix,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+		Search: opts.Search,
+	}
+	if body.Limit <= 0 {
+		body.Limit = defaultListLimit
+	}
+	if opts.SortBy.Column != "" {
+		sortBy := opts.SortBy
+		if sortBy.Order == "" {
+			sortBy.Order = "asc"
+		}
+		body.SortBy = &sortBy
+	}
+
+	var objects []FileObject
+	resp, err := s.client.RawRequest().
+		SetContext(ctx).
+		SetBody(body).
+		SetResult(&objects).
+		Post(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, newAPIError(resp)
+	}
+
+	return objects, nil
+}
+
+// Bucket represents a storage bucket
+type Bucket struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Owner            string    `json:"owner"`
+	Public           bool      `json:"public"`
+	FileSizeLimit    *int64    `json:"file_size_limit"`
+	AllowedMimeTypes []string  `json:"allowed_mime_types"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// BucketOptions configures a bucket on creation or update
+type BucketOptions struct {
+	Public bool
+	// FileSizeLimit is the maximum object size in bytes, 0 for no limit
+	FileSizeLimit int64
+	// AllowedMimeTypes restricts uploads, e.g. "image/png" or "image/*"
+	AllowedMimeTypes []string
+}
+
+// bucketRequest represents the request body for creating or updating a bucket
+type bucketRequest struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name,omitempty"`
+	Public           bool     `json:"public"`
+	FileSizeLimit    *int64   `json:"file_size_limit"`
+	AllowedMimeTypes []string `json:"allowed_mime_types"`
+}
+
+// newBucketRequest builds the request body for a bucket
+func newBucketRequest(id, name string, opts BucketOptions) bucketRequest {
+	req := bucketRequest{
+		ID:               id,
+		Name:             name,
+		Public:           opts.Public,
+		AllowedMimeTypes: opts.AllowedMimeTypes,
+	}
+	if opts.FileSizeLimit > 0 {
+		limit := opts.FileSizeLimit
+		req.FileSizeLimit = &limit
+	}
+	return req
+}
+
+// CreateBucket creates a bucket with the given id, which is also used as its name
+func (s *StorageClient) CreateBucket(ctx context.Context, id string, opts BucketOptions) error {
+	return s.client.Do(ctx, http.MethodPost, "/storage/v1/bucket", newBucketRequest(id, id, opts), nil)
+}
+
+// GetBucket retrieves a bucket by id
+func (s *StorageClient) GetBucket(ctx context.Context, id string) (*Bucket, error) {
+	var bucket Bucket
+	if err := s.client.Do(ctx, http.MethodGet, "/storage/v1/bucket/"+url.PathEscape(id), nil, &bucket); err != nil {
+		return nil, err
+	}
+	return &bucket, nil
+}
+
+// ListBuckets retrieves all buckets of the project
+func (s *StorageClient) ListBuckets(ctx context.Context) ([]Bucket, error) {
+	var buckets []Bucket
+	if err := s.client.Do(ctx, http.MethodGet, "/storage/v1/bucket", nil, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// UpdateBucket replaces the settings of a bucket
+func (s *StorageClient) UpdateBucket(ctx context.Context, id string, opts BucketOptions) error {
+	return s.client.Do(ctx, http.MethodPut, "/storage/v1/bucket/"+url.PathEscape(id), newBucketRequest(id, "", opts), nil)
+}
+
+// EmptyBucket deletes every object in a bucket
+func (s *StorageClient) EmptyBucket(ctx context.Context, id string) error {
+	return s.client.Do(ctx, http.MethodPost, "/storage/v1/bucket/"+url.PathEscape(id)+"/empty", nil, nil)
+}
+
+// DeleteBucket deletes a bucket, which must be empty
+func (s *StorageClient) DeleteBucket(ctx context.Context, id string) error {
+	return s.client.Do(ctx, http.MethodDelete, "/storage/v1/bucket/"+url.PathEscape(id), nil, nil)
+}
+
+// UploadOptions configures an object upload
+type UploadOptions struct {
+	// ContentType is the object's MIME type, application/octet-stream when empty
+	ContentType string
+	// CacheControl is the max-age in seconds for the Cache-Control header of downloads
+	CacheControl string
+	// Upsert replaces an existing object instead of failing
+	Upsert bool
+}
+
+// Upload streams r to path in a bucket without buffering it in memory
+func (s *StorageClient) Upload(ctx context.Context, bucket, path string, r io.Reader, opts UploadOptions) error {
+	endpoint := fmt.Sprintf("%s/storage/v1/object/%s/%s", s.client.GetBaseURL(), url.PathEscape(bucket), escapeObjectPath(path))
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	req := s.client.RawRequest().
+		SetContext(ctx).
+		SetHeader("Content-Type", contentType).
+		SetHeader("x-upsert", strconv.FormatBool(opts.Upsert)).
+		SetBody(r)
+	if opts.CacheControl != "" {
+		req.SetHeader("Cache-Control", "max-age="+opts.CacheControl)
+	}
+
+	resp, err := req.Post(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return newAPIError(resp)
+	}
+
+	return nil
+}
+
+// escapeObjectPath escapes each segment of an object path, keeping the slashes that
+// separate folders, so names with spaces, ? or # reach the right object
+func escapeObjectPath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// UploadWithProgress is like Upload, calling onProgress with the bytes sent so far and
+// size as the body is streamed, e.g. to drive a progress bar. size is only reported,
+// so pass -1 when it is unknown.
+func (s *StorageClient) UploadWithProgress(ctx context.Context, bucket, path string, r io.Reader, size int64, onProgress func(sent, total int64)) error {
+	return s.Upload(ctx, bucket, path, &progressReader{reader: r, total: size, onProgress: onProgress}, UploadOptions{})
+}
+
+// progressReader reports the bytes read from reader to onProgress
+type progressReader struct {
+	reader     io.Reader
+	sent       int64
+	total      int64
+	onProgress func(sent, total int64)
+}
+
+// Read implements io.Reader
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	if n > 0 {
+		p.sent += int64(n)
+		if p.onProgress != nil {
+			p.onProgress(p.sent, p.total)
+		}
+	}
+	return n, err
+}
+
+// tusChunkSize is the size of each PATCH in a resumable upload; Supabase Storage
+// requires 6MB chunks, except for the last one
+const tusChunkSize = 6 * 1024 * 1024
+
+// maxResumeAttempts is the number of consecutive failed chunks UploadResumable retries
+const maxResumeAttempts = 3
+
+// UploadResumable uploads size bytes from r to path in a bucket with the TUS protocol.
+// The upload is created first, then sent in chunks; when a chunk fails, the offset the
+// server received is fetched and the upload resumes from there, up to maxResumeAttempts
+// times in a row.
+func (s *StorageClient) UploadResumable(ctx context.Context, bucket, path string, r io.ReaderAt, size int64) error {
+	location, err := s.createResumableUpload(ctx, bucket, path, size)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	failures := 0
+	for offset < size {
+		next, err := s.patchResumableUpload(ctx, location, r, offset, size)
+		if err == nil && next <= offset {
+			return fmt.Errorf("resumable upload of %s/%s stopped at %d of %d bytes: server offset %d didn't advance", bucket, path, offset, size, next)
+		}
+		if err == nil {
+			offset = next
+			failures = 0
+			continue
+		}
+
+		failures++
+		if failures >= maxResumeAttempts || ctx.Err() != nil {
+			return fmt.Errorf("resumable upload of %s/%s stopped at %d of %d bytes: %w", bucket, path, offset, size, err)
+		}
+
+		// Resume from what the server actually stored
+		if offset, err = s.resumableUploadOffset(ctx, location); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tusRequest returns a request with the TUS protocol version header
+func (s *StorageClient) tusRequest(ctx context.Context) *resty.Request {
+	return s.client.RawRequest().
+		SetContext(ctx).
+		SetHeader("Tus-Resumable", "1.0.0")
+}
+
+// createResumableUpload creates a TUS upload and returns its URL
+func (s *StorageClient) createResumableUpload(ctx context.Context, bucket, path string, size int64) (string, error) {
+	encode := func(v string) string { return base64.StdEncoding.EncodeToString([]byte(v)) }
+	metadata := fmt.Sprintf("bucketName %s,objectName %s,contentType %s",
+		encode(bucket), encode(strings.TrimPrefix(path, "/")), encode("application/octet-stream"))
+
+	resp, err := s.tusRequest(ctx).
+		SetHeader("Upload-Length", strconv.FormatInt(size, 10)).
+		SetHeader("Upload-Metadata", metadata).
+		Post(s.client.GetBaseURL() + "/storage/v1/upload/resumable")
+	if err != nil {
+		return "", err
+	}
+
+	if resp.IsError() {
+		return "", newAPIError(resp)
+	}
+
+	location := resp.Header().Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("resumable upload of %s/%s: no upload URL returned", bucket, path)
+	}
+	if strings.HasPrefix(location, "/") {
+		location = s.client.GetBaseURL() + location
+	}
+
+	return location, nil
+}
+
+// patchResumableUpload sends the chunk starting at offset and returns the new offset
+func (s *StorageClient) patchResumableUpload(ctx context.Context, location string, r io.ReaderAt, offset, size int64) (int64, error) {
+	length := size - offset
+	if length > tusChunkSize {
+		length = tusChunkSize
+	}
+
+	resp, err := s.tusRequest(ctx).
+		SetHeader("Upload-Offset", strconv.FormatInt(offset, 10)).
+		SetHeader("Content-Type", "application/offset+octet-stream").
+		SetBody(io.NewSectionReader(r, offset, length)).
+		Patch(location)
+	if err != nil {
+		return offset, err
+	}
+
+	if resp.IsError() {
+		return offset, newAPIError(resp)
+	}
+
+	return strconv.ParseInt(resp.Header().Get("Upload-Offset"), 10, 64)
+}
+
+// resumableUploadOffset asks the server how many bytes of the upload it has stored
+func (s *StorageClient) resumableUploadOffset(ctx context.Context, location string) (int64, error) {
+	resp, err := s.tusRequest(ctx).Head(location)
+	if err != nil {
+		return 0, err
+	}
+
+	if resp.IsError() {
+		return 0, newAPIError(resp)
+	}
+
+	return strconv.ParseInt(resp.Header().Get("Upload-Offset"), 10, 64)
+}
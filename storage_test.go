@@ -0,0 +1,293 @@
+package supabaseorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStorageList(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"name":"2024","id":null,"updated_at":null,"created_at":null,"last_accessed_at":null,"metadata":null},
+			{"name":"report.pdf","id":"b1","updated_at":"2024-03-01T10:00:00Z","created_at":"2024-03-01T10:00:00Z","last_accessed_at":"2024-03-01T10:00:00Z","metadata":{"size":2048,"mimetype":"application/pdf","eTag":"\"abc\""}}
+		]`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "test-api-key")
+
+	objects, err := client.Storage().List(context.Background(), "documents", "reports/", ListOptions{
+		Limit:  50,
+		Offset: 100,
+		SortBy: SortBy{Column: "name", Order: "desc"},
+	})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if path != "/storage/v1/object/list/documents" {
+		t.Errorf("List() path = %v, want %v", path, "/storage/v1/object/list/documents")
+	}
+
+	expectedBody := map[string]interface{}{
+		"prefix": "reports/",
+		"limit":  float64(50),
+		"offset": float64(100),
+		"sortBy": map[string]interface{}{"column": "name", "order": "desc"},
+	}
+	if !reflect.DeepEqual(body, expectedBody) {
+		t.Errorf("List() body = %v, want %v", body, expectedBody)
+	}
+
+	if len(objects) != 2 {
+		t.Fatalf("List() returned %d objects, want 2", len(objects))
+	}
+	file := objects[1]
+	if file.Name != "report.pdf" || file.Metadata.Size != 2048 || file.Metadata.MimeType != "application/pdf" || file.UpdatedAt.IsZero() {
+		t.Errorf("List() object = %+v, want report.pdf metadata", file)
+	}
+}
+
+func TestStorageBuckets(t *testing.T) {
+	var method, path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		path = r.URL.Path
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/storage/v1/bucket":
+			w.Write([]byte(`{"name":"avatars"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/storage/v1/bucket/avatars":
+			w.Write([]byte(`{"message":"Successfully deleted"}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"statusCode":"404","error":"Bucket not found","message":"Bucket not found"}`))
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	storage := New(server.URL, "service-key").Storage()
+
+	err := storage.CreateBucket(context.Background(), "avatars", BucketOptions{
+		Public:           true,
+		FileSizeLimit:    1024 * 1024,
+		AllowedMimeTypes: []string{"image/png", "image/jpeg"},
+	})
+	if err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+
+	expectedBody := map[string]interface{}{
+		"id":                 "avatars",
+		"name":               "avatars",
+		"public":             true,
+		"file_size_limit":    float64(1024 * 1024),
+		"allowed_mime_types": []interface{}{"image/png", "image/jpeg"},
+	}
+	if method != http.MethodPost || path != "/storage/v1/bucket" || !reflect.DeepEqual(body, expectedBody) {
+		t.Errorf("CreateBucket() sent %s %s %v, want POST /storage/v1/bucket %v", method, path, body, expectedBody)
+	}
+
+	if err := storage.DeleteBucket(context.Background(), "avatars"); err != nil {
+		t.Fatalf("DeleteBucket() error = %v", err)
+	}
+	if method != http.MethodDelete || path != "/storage/v1/bucket/avatars" {
+		t.Errorf("DeleteBucket() sent %s %s, want DELETE /storage/v1/bucket/avatars", method, path)
+	}
+
+	err = storage.DeleteBucket(context.Background(), "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Bucket not found" {
+		t.Errorf("DeleteBucket() error = %v, want 404 APIError", err)
+	}
+}
+
+func TestUploadWithProgress(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	var received []byte
+	var path, contentType, upsert string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		upsert = r.Header.Get("x-upsert")
+		received, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Key":"backups/db.dump"}`))
+	}))
+	defer server.Close()
+
+	storage := New(server.URL, "test-api-key").Storage()
+
+	var calls []int64
+	err := storage.UploadWithProgress(context.Background(), "backups", "db.dump", bytes.NewReader(payload), int64(len(payload)), func(sent, total int64) {
+		if total != int64(len(payload)) {
+			t.Errorf("total = %d, want %d", total, len(payload))
+		}
+		calls = append(calls, sent)
+	})
+	if err != nil {
+		t.Fatalf("UploadWithProgress() error = %v", err)
+	}
+
+	if path != "/storage/v1/object/backups/db.dump" {
+		t.Errorf("path = %q", path)
+	}
+	if contentType != "application/octet-stream" || upsert != "false" {
+		t.Errorf("Content-Type = %q, x-upsert = %q", contentType, upsert)
+	}
+	if !bytes.Equal(received, payload) {
+		t.Errorf("received %d bytes, want %d", len(received), len(payload))
+	}
+
+	if len(calls) < 2 {
+		t.Fatalf("onProgress called %d times, want several as the body streams", len(calls))
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i] <= calls[i-1] {
+			t.Errorf("progress went from %d to %d, want increasing", calls[i-1], calls[i])
+		}
+	}
+	if last := calls[len(calls)-1]; last != int64(len(payload)) {
+		t.Errorf("last progress = %d, want %d", last, len(payload))
+	}
+}
+
+func TestUploadResumable(t *testing.T) {
+	payload := bytes.Repeat([]byte("resumable-"), 10*1024)
+
+	var stored []byte
+	var metadata string
+	patches, heads := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Tus-Resumable") != "1.0.0" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/storage/v1/upload/resumable":
+			metadata = r.Header.Get("Upload-Metadata")
+			w.Header().Set("Location", "/storage/v1/upload/resumable/upload-1")
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPatch && r.URL.Path == "/storage/v1/upload/resumable/upload-1":
+			patches++
+			if r.Header.Get("Upload-Offset") != strconv.Itoa(len(stored)) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			chunk, _ := io.ReadAll(r.Body)
+			if patches == 1 {
+				// The connection drops after part of the chunk was stored
+				stored = append(stored, chunk[:len(chunk)/3]...)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			stored = append(stored, chunk...)
+			w.Header().Set("Upload-Offset", strconv.Itoa(len(stored)))
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodHead && r.URL.Path == "/storage/v1/upload/resumable/upload-1":
+			heads++
+			w.Header().Set("Upload-Offset", strconv.Itoa(len(stored)))
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	storage := New(server.URL, "test-api-key").Storage()
+
+	err := storage.UploadResumable(context.Background(), "videos", "clip.mp4", bytes.NewReader(payload), int64(len(payload)))
+	if err != nil {
+		t.Fatalf("UploadResumable() error = %v", err)
+	}
+
+	if !bytes.Equal(stored, payload) {
+		t.Errorf("stored %d bytes, want the %d byte payload", len(stored), len(payload))
+	}
+	if patches != 2 || heads != 1 {
+		t.Errorf("sent %d patches and %d heads, want 2 and 1", patches, heads)
+	}
+
+	want := "bucketName dmlkZW9z,objectName Y2xpcC5tcDQ=,contentType YXBwbGljYXRpb24vb2N0ZXQtc3RyZWFt"
+	if metadata != want {
+		t.Errorf("Upload-Metadata = %q, want %q", metadata, want)
+	}
+}
+
+func TestStorageEscapesPaths(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	storage := New(server.URL, "test-api-key").Storage()
+	ctx := context.Background()
+
+	if err := storage.Upload(ctx, "team docs", "/2024 reports/q1?#draft.pdf", strings.NewReader("pdf"), UploadOptions{}); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if _, err := storage.GetBucket(ctx, "team docs"); err != nil {
+		t.Fatalf("GetBucket() error = %v", err)
+	}
+
+	want := []string{
+		"/storage/v1/object/team%20docs/2024%20reports/q1%3F%23draft.pdf",
+		"/storage/v1/bucket/team%20docs",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+}
+
+func TestUploadResumableStalled(t *testing.T) {
+	patches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Location", "/storage/v1/upload/resumable/upload-1")
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPatch:
+			patches++
+			// The chunk is accepted but the offset never moves
+			w.Header().Set("Upload-Offset", "0")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	storage := New(server.URL, "test-api-key").Storage()
+
+	err := storage.UploadResumable(context.Background(), "videos", "clip.mp4", strings.NewReader("resumable"), 9)
+	if err == nil || !strings.Contains(err.Error(), "didn't advance") {
+		t.Fatalf("UploadResumable() error = %v, want the stalled offset", err)
+	}
+	if patches != 1 {
+		t.Errorf("sent %d patches, want 1", patches)
+	}
+}
@@ -3,11 +3,18 @@ package supabaseorm
 import (
 	"fmt"
 	"net/http"
+	"sync/atomic"
 )
 
 // Transaction represents a database transaction
+// PostgREST runs each request in its own transaction, so writes made through separate
+// builders are committed one by one. Use RequireAtomic to fail instead of committing
+// a partial set of writes, and an RPC function for work that must be atomic.
 type Transaction struct {
-	client *Client
+	client        *Client
+	requireAtomic bool
+	// writes counts the writes sent so far; builders may run on several goroutines
+	writes atomic.Int64
 }
 
 // Begin starts a new transaction
@@ -26,11 +33,30 @@ func (t *Transaction) Table(tableName string) *QueryBuilder {
 	}
 
 	// Add transaction headers
-	builder.Header("Prefer", "tx=commit")
+	builder.prefer("tx=commit")
+	builder.tx = t
+	builder.requireAtomic = t.requireAtomic
 
 	return builder
 }
 
+// RequireAtomic makes every write after the first one fail with ErrNotAtomic,
+// since they would be committed in separate requests
+func (t *Transaction) RequireAtomic() *Transaction {
+	t.requireAtomic = true
+	return t
+}
+
+// checkWrite records a write made through the transaction, failing when it
+// is not the first and the transaction requires atomicity
+func (t *Transaction) checkWrite(q *QueryBuilder) error {
+	n := t.writes.Add(1)
+	if t.requireAtomic && n > 1 {
+		return fmt.Errorf("%w: %s on %s is write %d of the transaction", ErrNotAtomic, q.method, q.table, n)
+	}
+	return nil
+}
+
 // Commit commits the transaction
 // Note: In the current Supabase REST API, transactions are automatically committed
 // This is a placeholder for future functionality
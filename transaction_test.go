@@ -0,0 +1,95 @@
+package supabaseorm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAtomic(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "fake-api-key")
+
+	// Without RequireAtomic the writes are committed one by one
+	tx := client.Begin()
+	if err := tx.Table("users").Where("id", "eq", 1).Update(map[string]interface{}{"status": "archived"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := tx.Table("posts").Where("user_id", "eq", 1).Delete(); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	requests = 0
+	tx = client.Begin().RequireAtomic()
+	if err := tx.Table("users").Where("id", "eq", 1).Update(map[string]interface{}{"status": "archived"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	var posts []map[string]interface{}
+	if err := tx.Table("posts").Where("user_id", "eq", 1).Get(&posts); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if err := tx.Table("posts").Where("user_id", "eq", 1).Delete(); !errors.Is(err, ErrNotAtomic) {
+		t.Errorf("Delete() error = %v, want ErrNotAtomic", err)
+	}
+	if requests != 2 {
+		t.Errorf("sent %d requests, want 2 without the rejected delete", requests)
+	}
+
+	rows := []map[string]interface{}{{"id": 1}, {"id": 2}, {"id": 3}}
+	if _, err := client.Table("users").RequireAtomic().BatchInsert(rows, 2); !errors.Is(err, ErrNotAtomic) {
+		t.Errorf("BatchInsert() error = %v, want ErrNotAtomic", err)
+	}
+	if _, err := client.Table("users").RequireAtomic().BatchInsert(rows, 3); err != nil {
+		t.Errorf("BatchInsert() in one chunk error = %v", err)
+	}
+
+	user := &TestUser{Email: "john@example.com"}
+	if _, err := client.Table("users").RequireAtomic().InsertIfNotExists(user, "email"); !errors.Is(err, ErrNotAtomic) {
+		t.Errorf("InsertIfNotExists() error = %v, want ErrNotAtomic", err)
+	}
+}
+
+func TestRequireAtomicConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tx := New(server.URL, "fake-api-key").Begin().RequireAtomic()
+
+	const writers = 20
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			errs <- tx.Table("audit_log").Insert(map[string]interface{}{"n": i})
+		}(i)
+	}
+
+	sent := 0
+	for i := 0; i < writers; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			sent++
+		case !errors.Is(err, ErrNotAtomic):
+			t.Errorf("Insert() error = %v, want ErrNotAtomic", err)
+		}
+	}
+	if sent != 1 {
+		t.Errorf("%d writes were sent, want exactly 1", sent)
+	}
+}
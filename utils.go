@@ -1,6 +1,7 @@
 package supabaseorm
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -72,6 +73,21 @@ func BuildFilterCondition(column, operator string, value interface{}) string {
 	}
 }
 
+// contentRangeTotal returns the total from a Content-Range header such as 0-9/42. A
+// missing header or an unknown total (*) yields ErrCountUnavailable instead of zero.
+func contentRangeTotal(contentRange string) (int64, error) {
+	_, totalStr, found := strings.Cut(contentRange, "/")
+	if !found || totalStr == "*" {
+		return 0, fmt.Errorf("%w: Content-Range %q", ErrCountUnavailable, contentRange)
+	}
+
+	total, err := strconv.ParseInt(totalStr, 10, 64)
+	if err != nil || total < 0 {
+		return 0, fmt.Errorf("%w: Content-Range %q", ErrCountUnavailable, contentRange)
+	}
+	return total, nil
+}
+
 // ParseContentRange parses a Content-Range header
 func ParseContentRange(contentRange string) (start, end, total int) {
 	// Format: "items start-end/total"
@@ -96,3 +112,300 @@ func ParseContentRange(contentRange string) (start, end, total int) {
 
 	return start, end, total
 }
+
+// StructToMap converts a struct (or pointer to struct) into a map keyed by json column names.
+// Zero-valued fields are skipped when omitZero is set, when the json tag has omitempty,
+// or when the field is tagged supabase:"omitzero". Non-struct values are returned unchanged.
+func StructToMap(data interface{}, omitZero bool) interface{} {
+	return structToMap(data, omitZero, nil)
+}
+
+// structToMap is StructToMap with fields without a json name renamed by namer, if set
+func structToMap(data interface{}, omitZero bool, namer ColumnNamer) interface{} {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return data
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return data
+	}
+
+	result := make(map[string]interface{})
+	structFields(v, omitZero, namer, result)
+	return result
+}
+
+// structFields copies the exported fields of v into result, flattening embedded structs
+func structFields(v reflect.Value, omitZero bool, namer ColumnNamer, result map[string]interface{}) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// Like encoding/json, unexported embedded structs are flattened but not pointers to them
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// Flatten embedded structs without an explicit name, like encoding/json does;
+		// a nil embedded pointer contributes no fields
+		if field.Anonymous && name == "" {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				structFields(embedded, omitZero, namer, result)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+			if namer != nil {
+				name = namer(name)
+			}
+		}
+
+		skipZero := omitZero ||
+			hasTagOption(opts, "omitempty") ||
+			hasTagOption(field.Tag.Get("supabase"), "omitzero")
+		if skipZero && fieldValue.IsZero() {
+			continue
+		}
+
+		result[name] = fieldValue.Interface()
+
+		// The string option encodes scalars as JSON strings, e.g. an int64 id as "42"
+		if hasTagOption(opts, "string") {
+			switch fieldValue.Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64, reflect.String:
+				if raw, err := json.Marshal(fieldValue.Interface()); err == nil {
+					result[name] = string(raw)
+				}
+			}
+		}
+	}
+}
+
+// hasTagOption reports whether the comma-separated options of a json tag include option
+func hasTagOption(opts, option string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFilterTag splits a supabase struct tag into the column and operator that
+// WhereStruct filters on, e.g. "age,gte". The omitzero option only applies to updates
+// and is skipped, so a bare "omitzero" tag names no column.
+func parseFilterTag(tag string) (column, operator string) {
+	for i, part := range strings.Split(tag, ",") {
+		switch {
+		case part == "omitzero":
+		case i == 0:
+			column = part
+		case operator == "":
+			operator = part
+		}
+	}
+	if operator == "" {
+		operator = "eq"
+	}
+	return column, operator
+}
+
+// hasOmitZeroFields reports whether the struct type t, or a struct it embeds, has a
+// field tagged supabase:"omitzero"
+func hasOmitZeroFields(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if hasTagOption(field.Tag.Get("supabase"), "omitzero") {
+			return true
+		}
+		if field.Anonymous && hasOmitZeroFields(field.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedColumns are names that must be quoted to be read as columns: the query
+// parameters PostgREST reserves and common SQL keywords
+var reservedColumns = map[string]bool{
+	"select": true, "order": true, "limit": true, "offset": true, "columns": true, "on_conflict": true,
+	"and": true, "or": true, "not": true, "all": true, "any": true, "as": true, "asc": true, "desc": true,
+	"case": true, "check": true, "column": true, "default": true, "distinct": true, "else": true,
+	"end": true, "false": true, "for": true, "from": true, "group": true, "having": true, "in": true,
+	"is": true, "null": true, "on": true, "only": true, "table": true, "then": true, "to": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true, "when": true,
+	"where": true, "with": true,
+}
+
+// QuoteColumn double quotes a column name, e.g. for columns whose names clash with
+// PostgREST syntax. Select, Where and Order quote reserved words and mixed-case names
+// themselves.
+func QuoteColumn(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// quoteColumn quotes plain column names that are reserved words or contain uppercase
+// letters. Anything else, such as json paths, casts and already quoted names, is
+// returned unchanged.
+func quoteColumn(name string) string {
+	if !identifierPattern.MatchString(name) {
+		return name
+	}
+	if reservedColumns[strings.ToLower(name)] || strings.ToLower(name) != name {
+		return QuoteColumn(name)
+	}
+	return name
+}
+
+// unquoteColumn returns the plain name of a column quoted by quoteColumn
+func unquoteColumn(name string) string {
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// quoteColumns applies quoteColumn to each column
+func quoteColumns(columns []string) []string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteColumn(column)
+	}
+	return quoted
+}
+
+// formatOperand formats a filter value for the PostgREST query string.
+// Lists for the "in" operator are wrapped in parentheses, e.g. in.(1,2,3),
+// and booleans are written true and false.
+func formatOperand(operator string, value interface{}) string {
+	v := reflect.ValueOf(value)
+	if operator == "in" && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		items := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items[i] = formatListItem(v.Index(i).Interface())
+		}
+		return "(" + strings.Join(items, ",") + ")"
+	}
+
+	if b, ok := value.(bool); ok {
+		return strconv.FormatBool(b)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// listReservedChars are the characters that make PostgREST split or misread a list item
+const listReservedChars = ",.:()\" \\"
+
+// formatListItem formats one item of an in.(...) list. Strings containing reserved
+// characters are double quoted, with quotes and backslashes escaped.
+func formatListItem(item interface{}) string {
+	s, ok := item.(string)
+	if !ok {
+		return fmt.Sprintf("%v", item)
+	}
+
+	if !strings.ContainsAny(s, listReservedChars) {
+		return s
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+	return `"` + escaped + `"`
+}
+
+// arrayReservedChars are the characters that must be quoted in a Postgres array literal
+const arrayReservedChars = ",{}\" \\"
+
+// formatArray formats values as a Postgres array literal, e.g. {a,b}, as taken by the
+// eq(any) and eq(all) operators. Nil is written as NULL, and strings that are empty,
+// spell null or contain reserved characters are double quoted with quotes and
+// backslashes escaped.
+func formatArray(values []interface{}) string {
+	items := make([]string, len(values))
+	for i, value := range values {
+		s, ok := value.(string)
+		switch {
+		case value == nil:
+			items[i] = "NULL"
+		case !ok:
+			items[i] = fmt.Sprintf("%v", value)
+		case s == "" || strings.EqualFold(s, "null") || strings.ContainsAny(s, arrayReservedChars):
+			items[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		default:
+			items[i] = s
+		}
+	}
+	return "{" + strings.Join(items, ",") + "}"
+}
+
+// FilterTemplate is a precomputed filter on a column and operator that is bound
+// to a value per request, e.g. an "id eq ?" filter shared by a handler
+type FilterTemplate struct {
+	operator string
+	prefix   string
+	err      error
+}
+
+// NewFilterTemplate creates a FilterTemplate for the given column and operator.
+// The column and operator are validated once here; see Err.
+func NewFilterTemplate(column, operator string) *FilterTemplate {
+	t := &FilterTemplate{
+		operator: operator,
+		prefix:   column + "=" + operator + ".",
+	}
+	t.err = validateFilter(t.prefix)
+	return t
+}
+
+// Err returns the validation error of the template's column or operator, so a handler
+// can reject a bad template when it is set up rather than on its first request
+func (t *FilterTemplate) Err() error {
+	return t.err
+}
+
+// Bind returns the filter for value, in the column=operator.value form used by Where
+func (t *FilterTemplate) Bind(value interface{}) string {
+	return t.prefix + formatOperand(t.operator, value)
+}
+
+// WhereTemplate adds a filter by binding value to a FilterTemplate. An invalid
+// template adds its error to the builder instead, reported by Validate.
+func (q *QueryBuilder) WhereTemplate(t *FilterTemplate, value interface{}) *QueryBuilder {
+	if t.err != nil {
+		q.errs = append(q.errs, t.err)
+		return q
+	}
+	q.filters = append(q.filters, t.Bind(value))
+	return q
+}
@@ -0,0 +1,307 @@
+package supabaseorm
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// identifierPattern matches plain table and column names
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// jsonKeyPattern matches a json object key or array index in a path
+var jsonKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_$-]+$`)
+
+// castTypePattern matches a type name in a cast, e.g. numeric, int4 or text[]
+var castTypePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\[\])?$`)
+
+// filterOperators lists the operators PostgREST accepts in horizontal filters
+var filterOperators = map[string]bool{
+	"eq": true, "neq": true, "gt": true, "gte": true, "lt": true, "lte": true,
+	"like": true, "ilike": true, "match": true, "imatch": true,
+	"in": true, "is": true, "isdistinct": true,
+	"fts": true, "plfts": true, "phfts": true, "wfts": true,
+	"cs": true, "cd": true, "ov": true,
+	"sl": true, "sr": true, "nxr": true, "nxl": true, "adj": true,
+}
+
+// logicalFilters are the filter keys that hold a group of conditions
+var logicalFilters = map[string]bool{
+	"or": true, "and": true, "not.or": true, "not.and": true,
+}
+
+// Validate checks the query without sending it and returns every problem found:
+// unknown filter operators, invalid order directions, malformed identifiers,
+// and updates or deletes without any filter. The same checks run before execution.
+func (q *QueryBuilder) Validate() []error {
+	var errs []error
+	errs = append(errs, q.errs...)
+
+	if q.rawQuery == "" && !identifierPattern.MatchString(q.table) {
+		errs = append(errs, fmt.Errorf("invalid table name %q", q.table))
+	}
+
+	// A write whose only filters are invalid is as unguarded as one without any
+	validFilters := 0
+	for _, f := range q.allFilters() {
+		if err := validateFilter(f); err != nil {
+			errs = append(errs, err)
+		} else {
+			validFilters++
+		}
+	}
+
+	for _, top := range q.embedBuilders {
+		for _, e := range top.all() {
+			for _, f := range e.filters {
+				if err := validateFilter(f); err != nil {
+					errs = append(errs, fmt.Errorf("embed %s: %w", e.path(), err))
+				}
+			}
+			if e.order != "" {
+				if err := validateOrder(e.order); err != nil {
+					errs = append(errs, fmt.Errorf("embed %s: %w", e.path(), err))
+				}
+			}
+		}
+	}
+
+	for _, o := range q.foreignOrders {
+		if err := validateOrder(o.value); err != nil {
+			errs = append(errs, fmt.Errorf("embed %s: %w", o.key, err))
+		}
+	}
+
+	if q.orderQuery != "" {
+		if err := validateOrder(strings.TrimPrefix(q.orderQuery, "order=")); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if q.client != nil && q.client.defaultOrder != "" {
+		if err := validateOrder(q.client.defaultOrder); err != nil {
+			errs = append(errs, fmt.Errorf("default order: %w", err))
+		}
+	}
+
+	if c := q.client; c != nil && c.maxLimitStrict && c.maxLimit > 0 && q.limitQuery != "" &&
+		(q.method == http.MethodGet || q.method == http.MethodHead) {
+		if n, err := strconv.Atoi(strings.TrimPrefix(q.limitQuery, "limit=")); err == nil && n > c.maxLimit {
+			errs = append(errs, fmt.Errorf("%w: limit %d on %s, maximum is %d", ErrLimitExceeded, n, q.table, c.maxLimit))
+		}
+	}
+
+	if q.allowedColumns != nil {
+		errs = append(errs, q.validateColumns()...)
+	}
+
+	errs = append(errs, q.validateFeatures()...)
+
+	if err := q.validateFunction(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if q.readOnly && q.method != http.MethodGet && q.method != http.MethodHead {
+		errs = append(errs, fmt.Errorf("%w: %s on %s", ErrReadOnlyView, q.method, q.table))
+	}
+
+	if (q.method == http.MethodPatch || q.method == http.MethodDelete) && validFilters == 0 {
+		errs = append(errs, fmt.Errorf("%s on %s requires at least one filter", q.method, q.table))
+	}
+
+	return errs
+}
+
+// RestrictColumns limits the columns the query may select, filter and order on, so a
+// generic endpoint can build queries from untrusted input. Referencing any other column,
+// including a select of *, fails validation. Embedded resources count as columns.
+func (q *QueryBuilder) RestrictColumns(allowed ...string) *QueryBuilder {
+	q.allowedColumns = make(map[string]bool, len(allowed))
+	for _, column := range allowed {
+		q.allowedColumns[column] = true
+	}
+	return q
+}
+
+// validateColumns reports every column referenced outside the RestrictColumns allowlist
+func (q *QueryBuilder) validateColumns() []error {
+	var columns []string
+
+	if q.selectQuery != "" {
+		columns = append(columns, splitGroup(q.selectQuery)...)
+	}
+	columns = append(columns, q.embeds...)
+	for _, j := range q.joins {
+		columns = append(columns, j.foreignTable)
+	}
+	for _, e := range q.embedBuilders {
+		columns = append(columns, e.table)
+	}
+
+	for _, f := range q.allFilters() {
+		columns = append(columns, filterColumns(f)...)
+	}
+
+	if q.orderQuery != "" {
+		columns = append(columns, strings.Split(strings.TrimPrefix(q.orderQuery, "order="), ",")...)
+	}
+
+	var errs []error
+	for _, ref := range columns {
+		if column := baseColumn(ref); !q.allowedColumns[column] {
+			errs = append(errs, fmt.Errorf("column %q is not allowed on %s", column, q.table))
+		}
+	}
+
+	return errs
+}
+
+// filterColumns returns the columns a filter references, including those in logical groups
+func filterColumns(f string) []string {
+	key, value, _ := strings.Cut(f, "=")
+	if !logicalFilters[key] {
+		return []string{key}
+	}
+
+	var columns []string
+	if len(value) >= 2 {
+		for _, condition := range splitGroup(value[1 : len(value)-1]) {
+			if name, nested, found := strings.Cut(condition, "("); found && logicalFilters[name] {
+				columns = append(columns, filterColumns(name+"=("+nested)...)
+				continue
+			}
+			column, _, _ := strings.Cut(condition, ".")
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+// baseColumn strips aliases, spreads, casts, json paths, aggregates and embedded
+// column lists from a column reference, e.g. "tier:metadata->>tier" becomes "metadata"
+func baseColumn(ref string) string {
+	ref = strings.TrimPrefix(strings.TrimSpace(ref), "...")
+
+	// Quoted names end at the closing quote, e.g. "order".desc
+	if strings.HasPrefix(ref, `"`) {
+		if end := strings.Index(ref[1:], `"`); end >= 0 {
+			return ref[1 : end+1]
+		}
+	}
+	if alias, rest, found := strings.Cut(ref, ":"); found && !strings.HasPrefix(rest, ":") && identifierPattern.MatchString(alias) {
+		ref = rest
+	}
+
+	if i := strings.IndexAny(ref, ".(:-!"); i >= 0 {
+		ref = ref[:i]
+	}
+
+	return ref
+}
+
+// validateFilter checks a filter of the form column=operator.value
+func validateFilter(f string) error {
+	column, value, ok := strings.Cut(f, "=")
+	if !ok || column == "" {
+		return fmt.Errorf("malformed filter %q", f)
+	}
+
+	if logicalFilters[column] {
+		return validateGroup(column, value)
+	}
+
+	if strings.ContainsAny(column, "&?#,()= ") {
+		return fmt.Errorf("invalid column name %q", column)
+	}
+
+	value = strings.TrimPrefix(value, "not.")
+	operator, _, _ := strings.Cut(value, ".")
+
+	// Array operators carry a modifier, e.g. eq(any)
+	if base, _, found := strings.Cut(operator, "("); found {
+		operator = base
+	}
+
+	if !filterOperators[operator] {
+		return fmt.Errorf("unknown operator %q in filter on %s", operator, column)
+	}
+
+	return nil
+}
+
+// validateGroup checks the conditions of a logical filter, e.g. or=(age.lt.18,and(a.eq.1,b.eq.2))
+func validateGroup(key, group string) error {
+	if !strings.HasPrefix(group, "(") || !strings.HasSuffix(group, ")") {
+		return fmt.Errorf("malformed %s group %q", key, group)
+	}
+
+	for _, condition := range splitGroup(group[1 : len(group)-1]) {
+		// Nested groups are written as or(...), and(...), not.or(...) or not.and(...)
+		if name, nested, found := strings.Cut(condition, "("); found && logicalFilters[name] {
+			if err := validateGroup(name, "("+nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		column, rest, found := strings.Cut(condition, ".")
+		if !found {
+			return fmt.Errorf("malformed condition %q in %s group", condition, key)
+		}
+
+		if err := validateFilter(column + "=" + rest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitGroup splits the conditions of a logical group on commas that are
+// not nested in parentheses or double quotes
+func splitGroup(group string) []string {
+	var conditions []string
+	depth, start, quoted := 0, 0, false
+
+	for i := 0; i < len(group); i++ {
+		switch c := group[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			conditions = append(conditions, group[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(conditions, group[start:])
+}
+
+// validateOrder checks an order clause of the form column.direction[.nulls],...
+func validateOrder(order string) error {
+	for _, term := range strings.Split(order, ",") {
+		parts := strings.Split(term, ".")
+		if parts[0] == "" {
+			return fmt.Errorf("missing order column in %q", term)
+		}
+
+		for _, modifier := range parts[1:] {
+			switch modifier {
+			case "asc", "desc", "nullsfirst", "nullslast":
+			default:
+				return fmt.Errorf("invalid order direction %q for %s", modifier, parts[0])
+			}
+		}
+	}
+
+	return nil
+}